@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -14,27 +13,92 @@ import (
 	"github.com/ad3n/kmt/v2/pkg/config"
 	"github.com/ad3n/kmt/v2/pkg/db"
 
-	"github.com/aquasecurity/table"
 	"github.com/fatih/color"
 	"github.com/urfave/cli/v3"
 )
 
 func main() {
-	cfg := config.Parse(config.CONFIG_FILE)
+	cfg := &config.Config{}
+	loadConfig := func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+		loaded, err := config.Load(cmd.String("config"), cmd.String("env"))
+		if err != nil {
+			return ctx, err
+		}
+
+		*cfg = *loaded
+
+		switch {
+		case cmd.Bool("wait") && cmd.Bool("no-wait"):
+			return ctx, config.ConfigError("--wait and --no-wait cannot be used together")
+		case cmd.Bool("wait"):
+			cfg.Migration.LockWaitTimeout = -1
+		case cmd.Bool("no-wait"):
+			cfg.Migration.LockWaitTimeout = 0
+		}
+
+		return ctx, nil
+	}
+
 	app := &cli.Command{
 		Name:                   "kmt",
 		Usage:                  "Kejawen Migration Tool (KMT)",
 		Description:            "kmt help",
 		UseShortOptionHandling: true,
+		ExitErrHandler: func(ctx context.Context, cmd *cli.Command, err error) {
+			var exit cli.ExitCoder
+			if errors.As(err, &exit) {
+				config.ErrorColor.Fprintln(os.Stderr, err.Error())
+				os.Exit(exit.ExitCode())
+			}
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Aliases: []string{"c"},
+				Sources: cli.EnvVars("KMT_CONFIG"),
+				Usage:   "path to Kmtfile.yml, default is searched from the current directory upward",
+			},
+			&cli.StringFlag{
+				Name:    "env",
+				Aliases: []string{"e"},
+				Sources: cli.EnvVars("KMT_ENV"),
+				Usage:   "deep merge Kmtfile.<env>.yml over the Kmtfile",
+			},
+			&cli.BoolFlag{
+				Name:  "wait",
+				Usage: "wait for the migration lock held by another kmt without timeout",
+			},
+			&cli.BoolFlag{
+				Name:  "no-wait",
+				Usage: "fail immediately when the migration lock is held by another kmt",
+			},
+			&cli.StringFlag{
+				Name:      "output",
+				Value:     OUTPUT_TABLE,
+				Usage:     "output format for version, compare and inspect: table, json or yaml",
+				Validator: validateOutput,
+			},
+		},
 		Commands: []*cli.Command{
 			{
 				Name:        "sync",
+				Before:      loadConfig,
 				Aliases:     []string{"sy"},
 				Description: "sync <connection> <cluster> <schema>",
 				Usage:       "Set the <cluster> <schema> to <connection> version",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "show pending migration file(s) without running them",
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 3 {
-						return errors.New("not enough arguments. Usage: kmt sync <connection> <cluster> <schema>")
+						return config.ConfigError("not enough arguments. Usage: kmt sync <connection> <cluster> <schema>")
+					}
+
+					if cmd.Bool("dry-run") {
+						return command.NewSync(cfg.Migration).DryRun(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Get(2))
 					}
 
 					return command.NewSync(cfg.Migration).Run(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Get(2))
@@ -42,11 +106,22 @@ func main() {
 			},
 			{
 				Name:        "up",
+				Before:      loadConfig,
 				Description: "up <connection> <schema>",
 				Usage:       "Migration up",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "show pending migration file(s) without running them",
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 2 {
-						return errors.New("not enough arguments. Usage: kmt up <connection> <schema>")
+						return config.ConfigError("not enough arguments. Usage: kmt up <connection> <schema>")
+					}
+
+					if cmd.Bool("dry-run") {
+						return command.NewUp(cfg.Migration).DryRun(cmd.Args().Get(0), cmd.Args().Get(1))
 					}
 
 					return command.NewUp(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1))
@@ -54,12 +129,23 @@ func main() {
 			},
 			{
 				Name:        "make",
+				Before:      loadConfig,
 				Aliases:     []string{"mk"},
 				Description: "make <schema> <connection> <destination>",
 				Usage:       "Make <schema> on the <destination> has same version with the <connection>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "show pending migration file(s) without running them",
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 3 {
-						return errors.New("not enough arguments. Usage: kmt make <schema> <connection> <destination>")
+						return config.ConfigError("not enough arguments. Usage: kmt make <schema> <connection> <destination>")
+					}
+
+					if cmd.Bool("dry-run") {
+						return command.NewCopy(cfg.Migration).DryRun(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Get(2))
 					}
 
 					return command.NewCopy(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Get(2))
@@ -67,19 +153,18 @@ func main() {
 			},
 			{
 				Name:        "rollback",
+				Before:      loadConfig,
 				Aliases:     []string{"rb"},
 				Description: "rollback <connection> <schema> <step>",
 				Usage:       "Rollback migration on <connection> <schema> for <step> step(s)",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 3 {
-						return errors.New("not enough arguments. Usage: kmt rollback <connection> <schema> <step>")
+						return config.ConfigError("not enough arguments. Usage: kmt rollback <connection> <schema> <step>")
 					}
 
 					n, err := strconv.ParseInt(cmd.Args().Get(2), 10, 0)
 					if err != nil {
-						config.ErrorColor.Println("Step is not number")
-
-						return nil
+						return config.ConfigError("step is not number")
 					}
 
 					return command.NewRollback(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1), int(n))
@@ -87,19 +172,28 @@ func main() {
 			},
 			{
 				Name:        "run",
+				Before:      loadConfig,
 				Aliases:     []string{"rn"},
 				Description: "run <connection> <schema> <step>",
 				Usage:       "Run migration on <connection> <schema> for <step> step(s)",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "show pending migration file(s) without running them",
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 3 {
-						return errors.New("not enough arguments. Usage: kmt run <connection> <schema> <step>")
+						return config.ConfigError("not enough arguments. Usage: kmt run <connection> <schema> <step>")
 					}
 
 					n, err := strconv.ParseInt(cmd.Args().Get(2), 10, 0)
 					if err != nil {
-						config.ErrorColor.Println("Step is not number")
+						return config.ConfigError("step is not number")
+					}
 
-						return nil
+					if cmd.Bool("dry-run") {
+						return command.NewRun(cfg.Migration).DryRun(cmd.Args().Get(0), cmd.Args().Get(1), int(n))
 					}
 
 					return command.NewRun(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1), int(n))
@@ -107,19 +201,18 @@ func main() {
 			},
 			{
 				Name:        "set",
+				Before:      loadConfig,
 				Aliases:     []string{"st"},
 				Description: "set <connection> <schema> <version>",
 				Usage:       "Set migration on <connection> <schema> to <version> without running migration file(s)",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 3 {
-						return errors.New("not enough arguments. Usage: kmt set <connection> <schema> <version>")
+						return config.ConfigError("not enough arguments. Usage: kmt set <connection> <schema> <version>")
 					}
 
 					n, err := strconv.ParseInt(cmd.Args().Get(2), 10, 0)
 					if err != nil {
-						config.ErrorColor.Println("Version is not number")
-
-						return nil
+						return config.ConfigError("version is not number")
 					}
 
 					return command.NewSet(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1), int(n))
@@ -127,32 +220,76 @@ func main() {
 			},
 			{
 				Name:        "migrate",
+				Before:      loadConfig,
 				Aliases:     []string{"mg"},
 				Description: "migrate <connection> <schema> <version>",
 				Usage:       "Migrate <connection> <schema> to specific <version>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "show pending migration file(s) without running them",
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 3 {
-						return errors.New("not enough arguments. Usage: kmt migrate <connection> <schema> <version>")
+						return config.ConfigError("not enough arguments. Usage: kmt migrate <connection> <schema> <version>")
 					}
 
 					n, err := strconv.ParseInt(cmd.Args().Get(2), 10, 0)
 					if err != nil {
-						config.ErrorColor.Println("Version is not number")
+						return config.ConfigError("version is not number")
+					}
 
-						return nil
+					if cmd.Bool("dry-run") {
+						return command.NewMigrate(cfg.Migration).DryRun(cmd.Args().Get(0), cmd.Args().Get(1), int(n))
 					}
 
 					return command.NewMigrate(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1), int(n))
 				},
 			},
+			{
+				Name:        "plan",
+				Before:      loadConfig,
+				Description: "plan <connection> <schema> [-o plan.json]",
+				Usage:       "Save pending migrations for <connection> <schema> with their checksums",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"o", "f"},
+						Value:   "plan.json",
+						Usage:   "plan file to write",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 2 {
+						return config.ConfigError("not enough arguments. Usage: kmt plan <connection> <schema> [-o plan.json]")
+					}
+
+					return command.NewPlan(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1), cmd.String("file"))
+				},
+			},
+			{
+				Name:        "apply",
+				Before:      loadConfig,
+				Description: "apply <plan>",
+				Usage:       "Apply a saved <plan> when database version and file checksums are unchanged",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 1 {
+						return config.ConfigError("not enough arguments. Usage: kmt apply <plan>")
+					}
+
+					return command.NewApply(cfg.Migration).Call(cmd.Args().Get(0))
+				},
+			},
 			{
 				Name:        "down",
+				Before:      loadConfig,
 				Aliases:     []string{"dw"},
 				Description: "down <connection> <schema>",
 				Usage:       "Downing migration on <connection> <schema>",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 2 {
-						return errors.New("not enough arguments. Usage: kmt down <connection> <schema>")
+						return config.ConfigError("not enough arguments. Usage: kmt down <connection> <schema>")
 					}
 
 					return command.NewDown(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1))
@@ -160,12 +297,13 @@ func main() {
 			},
 			{
 				Name:        "drop",
+				Before:      loadConfig,
 				Aliases:     []string{"dp"},
 				Description: "drop <connection> <schema>",
 				Usage:       "Dropping migration on <connection> <schema>",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 2 {
-						return errors.New("not enough arguments. Usage: kmt drop <connection> <schema>")
+						return config.ConfigError("not enough arguments. Usage: kmt drop <connection> <schema>")
 					}
 
 					return command.NewDrop(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1))
@@ -173,12 +311,13 @@ func main() {
 			},
 			{
 				Name:        "clean",
+				Before:      loadConfig,
 				Aliases:     []string{"cl"},
 				Description: "clean <connection> <schema>",
 				Usage:       "Clean dirty migration on <connection> <schema>",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 2 {
-						return errors.New("not enough arguments. Usage: kmt clean <connection> <schema>")
+						return config.ConfigError("not enough arguments. Usage: kmt clean <connection> <schema>")
 					}
 
 					return command.NewClean(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1))
@@ -186,19 +325,84 @@ func main() {
 			},
 			{
 				Name:        "create",
+				Before:      loadConfig,
 				Aliases:     []string{"cr"},
 				Description: "create <schema> <name>",
 				Usage:       "Create new migration files for <schema> with name <name>",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 2 {
-						return errors.New("not enough arguments. Usage: kmt create <schema> <name>")
+						return config.ConfigError("not enough arguments. Usage: kmt create <schema> <name>")
 					}
 
 					return command.NewCreate(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1))
 				},
 			},
+			{
+				Name:    "baseline",
+				Before:  loadConfig,
+				Aliases: []string{"bl"},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "cluster",
+						Usage: "mark the baseline applied on every connection of the cluster",
+					},
+				},
+				Description: "baseline <connection> <schema> [--cluster=<cluster>]",
+				Usage:       "Create a baseline migration from <schema> on <connection> and mark it applied",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 2 {
+						return config.ConfigError("not enough arguments. Usage: kmt baseline <connection> <schema> [--cluster=<cluster>]")
+					}
+
+					return command.NewBaseline(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1), cmd.String("cluster"))
+				},
+			},
+			{
+				Name:    "squash",
+				Before:  loadConfig,
+				Aliases: []string{"sq"},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "until",
+						Usage: "last version to squash",
+					},
+					&cli.StringFlag{
+						Name:  "connection",
+						Usage: "connection whose server hosts the scratch database, required when the schema is on more than one connection",
+					},
+				},
+				Description: "squash <schema> --until=<version> [--connection=<connection>]",
+				Usage:       "Squash migrations for <schema> up to <version> into one migration built on a scratch database",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 1 || cmd.String("until") == "" {
+						return config.ConfigError("not enough arguments. Usage: kmt squash <schema> --until=<version> [--connection=<connection>]")
+					}
+
+					n, err := strconv.ParseUint(cmd.String("until"), 10, 0)
+					if err != nil {
+						return config.ConfigError("version is not number")
+					}
+
+					return command.NewSquash(cfg.Migration).Call(cmd.String("connection"), cmd.Args().Get(0), uint(n))
+				},
+			},
+			{
+				Name:        "convert",
+				Before:      loadConfig,
+				Aliases:     []string{"cv"},
+				Description: "convert <schema> <single|split>",
+				Usage:       "Convert migration files for <schema> to the single or split layout",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 2 {
+						return config.ConfigError("not enough arguments. Usage: kmt convert <schema> <single|split>")
+					}
+
+					return command.NewConvert(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1))
+				},
+			},
 			{
 				Name:    "generate",
+				Before:  loadConfig,
 				Aliases: []string{"gn"},
 				Flags: []cli.Flag{
 					&cli.StringFlag{
@@ -225,18 +429,30 @@ func main() {
 						Name:  "include-data",
 						Usage: "include data option when table option active",
 					},
+					&cli.BoolFlag{
+						Name:  "pg-dump",
+						Usage: "use pg_dump instead of pg_catalog to generate table migration file(s)",
+					},
+					&cli.BoolFlag{
+						Name:  "concurrent-index",
+						Usage: "write table indexes as separate CREATE INDEX CONCURRENTLY migration file(s) without transaction",
+					},
+					&cli.BoolFlag{
+						Name:  "repeatable",
+						Usage: "write functions and views into the repeatable folder instead of versioned migration file(s)",
+					},
 				},
-				Description: "generate <connection> [<schema> [--table=<tables> --view=<views> --function=<functions> --mview=<mviews> --include-data]",
-				Usage:       "Generate migrations from <connection> on <schema> with options [--table=<tables> --view=<views> --function=<functions> --mview=<mviews> --include-data]",
+				Description: "generate <connection> [<schema> [--table=<tables> --view=<views> --function=<functions> --mview=<mviews> --include-data --pg-dump --concurrent-index --repeatable]",
+				Usage:       "Generate migrations from <connection> on <schema> with options [--table=<tables> --view=<views> --function=<functions> --mview=<mviews> --include-data --pg-dump --concurrent-index --repeatable]",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 1 {
-						return errors.New("not enough arguments. Usage: kmt generate <connection> [<schema> [--table=<tables> --view=<views> --function=<functions> --mview=<mviews> --include-data]")
+						return config.ConfigError("not enough arguments. Usage: kmt generate <connection> [<schema> [--table=<tables> --view=<views> --function=<functions> --mview=<mviews> --include-data --pg-dump --concurrent-index --repeatable]")
 					}
 
 					connection := cmd.Args().Get(0)
 					source, ok := cfg.Migration.Connections[connection]
 					if !ok {
-						return fmt.Errorf("connection '%s' not found", connection)
+						return config.ConfigError("connection '%s' not found", connection)
 					}
 
 					db, err := config.NewConnection(source)
@@ -247,16 +463,18 @@ func main() {
 
 					cmdGenerate := command.NewGenerate(cfg.Migration, db)
 					args := cmd.Args().Slice()
-					if len(args) == 0 {
+					if len(args) == 1 {
 						for schema := range source.Schemas {
-							cmdGenerate.Call(connection, schema, &command.GenerateScope{})
+							if err := cmdGenerate.Call(connection, schema, &command.GenerateScope{PgDump: cmd.Bool("pg-dump"), ConcurrentIndex: cmd.Bool("concurrent-index"), Repeatable: cmd.Bool("repeatable")}); err != nil {
+								return err
+							}
 						}
 
 						return nil
 					}
 
 					schema := args[1]
-					scope := &command.GenerateScope{}
+					scope := &command.GenerateScope{PgDump: cmd.Bool("pg-dump"), ConcurrentIndex: cmd.Bool("concurrent-index"), Repeatable: cmd.Bool("repeatable")}
 					if table := cmd.String("table"); table != "" {
 						scope.Tables = strings.Split(table, ",")
 						scope.IncludeData = cmd.Bool("include-data")
@@ -281,219 +499,170 @@ func main() {
 					return cmdGenerate.Call(connection, schema, scope)
 				},
 			},
+			{
+				Name:        "diff",
+				Before:      loadConfig,
+				Aliases:     []string{"df"},
+				Description: "diff <source> <target> <schema>",
+				Usage:       "Create migration files to make <schema> on <target> same with <source>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 3 {
+						return config.ConfigError("not enough arguments. Usage: kmt diff <source> <target> <schema>")
+					}
+
+					return command.NewDiff(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Get(2))
+				},
+			},
 			{
 				Name:        "version",
+				Before:      loadConfig,
 				Aliases:     []string{"v"},
 				Description: "version <connection>|<cluster> [<schema>]",
 				Usage:       "Show migration version on <connection>|<cluster> [<schema>]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "check",
+						Usage: "exit with non-zero code when any connection is behind the migration files",
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 1 {
-						return errors.New("not enough arguments. Usage: kmt version <connection>|<cluster> [<schema>]")
+						return config.ConfigError("not enough arguments. Usage: kmt version <connection>|<cluster> [<schema>]")
 					}
 
-					cmdVersion := command.NewVersion(cfg.Migration)
-
-					t := table.New(os.Stdout)
-					t.SetHeaderStyle(table.StyleBold)
-					t.SetLineStyle(table.StyleBrightBlack)
-					t.SetDividers(table.UnicodeRoundedDividers)
-					t.AddHeaders("NO", "CONNECTION", "SCHEMA", "FILE", "VERSION", "SYNC", "DIFF")
-
-					if cmd.NArg() == 2 {
-						db := cmd.Args().Get(0)
-						schema := cmd.Args().Get(1)
-						vDb, vFile, diff := cmdVersion.Call(db, schema)
-						if vDb == 0 || vFile == 0 {
-							return nil
-						}
-
-						sync := vFile == vDb
-						var status string
-						if sync {
-							status = color.New(color.FgGreen).Sprint("v")
-						} else {
-							status = color.New(color.FgRed, color.Bold).Sprint("x")
-						}
-
-						t.AddRow("1", db, schema, strconv.Itoa(int(vFile)), strconv.Itoa(int(vDb)), status, strconv.Itoa(diff))
-						t.Render()
-
-						return nil
+					records, err := command.NewVersion(cfg.Migration).Records(cmd.Args().Get(0), cmd.Args().Get(1))
+					if err != nil {
+						return err
 					}
 
-					number := 1
-					db := cmd.Args().Get(0)
-					clusters, ok := cfg.Migration.Clusters[db]
-					if !ok {
-						source, ok := cfg.Migration.Connections[db]
-						if !ok {
-							return fmt.Errorf("cluster/connection '%s' not found", db)
-						}
-
-						for k := range source.Schemas {
-							vDb, vFile, diff := cmdVersion.Call(db, k)
-							if vDb == 0 || vFile == 0 {
-								return nil
-							}
-
-							sync := vFile == vDb
-							var status string
-							if sync {
-								status = color.New(color.FgGreen).Sprint("v")
-							} else {
-								status = color.New(color.FgRed, color.Bold).Sprint("x")
-							}
-
-							t.AddRow(color.New(color.Bold).Sprint(number), db, k, strconv.Itoa(int(vFile)), strconv.Itoa(int(vDb)), status, strconv.Itoa(diff))
-
-							number++
-						}
-
-						t.Render()
-
-						return nil
+					err = render(cmd.String("output"), records, func() {
+						versionTable(records)
+					})
+					if err != nil || !cmd.Bool("check") {
+						return err
 					}
 
-					for _, c := range clusters {
-						source, ok := cfg.Migration.Connections[c]
-						if !ok {
-							return fmt.Errorf("connection for '%s' not found", c)
-						}
-
-						for k := range source.Schemas {
-							vDb, vFile, diff := cmdVersion.Call(c, k)
-							if vDb == 0 || vFile == 0 {
-								return nil
-							}
-
-							sync := vFile == vDb
-							var status string
-							if sync {
-								status = color.New(color.FgGreen).Sprint("v")
-							} else {
-								status = color.New(color.FgRed, color.Bold).Sprint("x")
-							}
-
-							t.AddRow(color.New(color.Bold).Sprint(number), c, k, strconv.Itoa(int(vFile)), strconv.Itoa(int(vDb)), status, strconv.Itoa(diff))
-
-							number++
+					behind := 0
+					for _, record := range records {
+						if record.Database < record.File {
+							behind++
 						}
 					}
 
-					t.Render()
+					if behind > 0 {
+						return config.OutOfSyncError("%d schema(s) behind the migration files", behind)
+					}
 
 					return nil
 				},
 			},
 			{
 				Name:        "compare",
+				Before:      loadConfig,
 				Aliases:     []string{"c"},
 				Description: "compare <connection1> <connection2> [<schema>]",
 				Usage:       "Compare migration <connection1> with <connection2> on [<schema>]",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 2 {
-						return errors.New("not enough arguments. Usage: kmt compare <connection1> <connection2> [<schema>]")
+						return config.ConfigError("not enough arguments. Usage: kmt compare <connection1> <connection2> [<schema>]")
 					}
 
-					cmdCompare := command.NewCompare(cfg.Migration)
-
-					t := table.New(os.Stdout)
-					t.SetHeaderStyle(table.StyleBold)
-					t.SetLineStyle(table.StyleBrightBlack)
-					t.SetDividers(table.UnicodeRoundedDividers)
-
-					source, ok := cfg.Migration.Connections[cmd.Args().Get(0)]
-					if !ok {
-						return fmt.Errorf("connection '%s' not found", cmd.Args().Get(0))
+					records, err := command.NewCompare(cfg.Migration).Records(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Get(2))
+					if err != nil {
+						return err
 					}
 
-					compare, ok := cfg.Migration.Connections[cmd.Args().Get(1)]
-					if !ok {
-						return fmt.Errorf("connection '%s' not found", cmd.Args().Get(1))
+					return render(cmd.String("output"), records, func() {
+						compareTable(cmd.Args().Get(0), cmd.Args().Get(1), records)
+					})
+				},
+			},
+			{
+				Name:        "history",
+				Before:      loadConfig,
+				Aliases:     []string{"hs"},
+				Description: "history <connection> <schema>",
+				Usage:       "Show applied, rolled back, failed and set migration(s) on <connection> <schema>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 2 {
+						return config.ConfigError("not enough arguments. Usage: kmt history <connection> <schema>")
 					}
 
-					if cmd.NArg() == 3 {
-						t.SetHeaders("NO", "SCHEMA", strings.ToUpper(cmd.Args().Get(0)), strings.ToUpper(cmd.Args().Get(1)), "SYNC", "DIFF")
-
-						schema := cmd.Args().Get(2)
-						_, ok := source.Schemas[schema]
-						if !ok {
-							return fmt.Errorf("schema '%s' not found on %s", schema, cmd.Args().Get(0))
-						}
+					histories, err := command.NewHistory(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1))
+					if err != nil || len(histories) == 0 {
+						return err
+					}
 
-						_, ok = compare.Schemas[schema]
-						if !ok {
-							return fmt.Errorf("schema '%s' not found on %s", schema, cmd.Args().Get(1))
-						}
+					t := newTable()
+					t.AddHeaders("NO", "VERSION", "NAME", "APPLIED AT", "DURATION", "ATTEMPTS", "APPLIED BY", "KMT", "STATUS")
 
-						vSource, vCompare, diff := cmdCompare.Call(cmd.Args().Get(0), cmd.Args().Get(1), schema)
-						if vSource == 0 {
-							return nil
-						}
-
-						sync := vSource == vCompare
+					for i, history := range histories {
 						var status string
-						if sync {
-							status = color.New(color.FgGreen).Sprint("v")
-						} else {
-							status = color.New(color.FgRed, color.Bold).Sprint("x")
+						switch history.Status {
+						case config.HISTORY_APPLIED:
+							status = color.New(color.FgGreen).Sprint(history.Status)
+						case config.HISTORY_FAILED:
+							status = color.New(color.FgRed, color.Bold).Sprint(history.Status)
+						default:
+							status = color.New(color.FgYellow).Sprint(history.Status)
 						}
 
-						t.AddRow("1", schema, strconv.Itoa(int(vSource)), strconv.Itoa(int(vCompare)), status, strconv.Itoa(diff))
-						t.Render()
-
-						return nil
+						t.AddRow(
+							color.New(color.Bold).Sprint(i+1),
+							strconv.Itoa(int(history.Version)),
+							history.Name,
+							history.AppliedAt.Local().Format("2006-01-02 15:04:05"),
+							history.Duration.String(),
+							attempts(history),
+							history.OsUser,
+							history.KmtVersion,
+							status,
+						)
 					}
 
-					number := 1
-					t.SetHeaders("NO", "SCHEMA", "FILE", strings.ToUpper(cmd.Args().Get(0)), strings.ToUpper(cmd.Args().Get(1)), "SYNC", "DIFF")
-					for k := range source.Schemas {
-						for l := range compare.Schemas {
-							if k != l {
-								continue
-							}
-
-							vSource, vCompare, diff := cmdCompare.Call(cmd.Args().Get(0), cmd.Args().Get(1), k)
-							if vSource == 0 {
-								return nil
-							}
-
-							files, err := os.ReadDir(filepath.Join(cfg.Migration.Folder, k))
-							if err != nil {
-								fmt.Println(err.Error())
-
-								return nil
-							}
-
-							filesLength := len(files)
-							if filesLength == 0 {
-								return nil
-							}
+					t.Render()
 
-							file := strings.Split(files[filesLength-1].Name(), "_")
-							version, _ := strconv.Atoi(file[0])
+					return nil
+				},
+			},
+			{
+				Name:        "verify",
+				Before:      loadConfig,
+				Aliases:     []string{"vf"},
+				Description: "verify <connection> <schema>",
+				Usage:       "Report applied migration file(s) on <connection> <schema> that changed after they were applied",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 2 {
+						return config.ConfigError("not enough arguments. Usage: kmt verify <connection> <schema>")
+					}
 
-							sync := uint(version) == vSource && vSource == vCompare
-							var status string
-							if sync {
-								status = color.New(color.FgGreen).Sprint("v")
-							} else {
-								status = color.New(color.FgRed, color.Bold).Sprint("x")
-							}
+					drifts, err := command.NewVerify(cfg.Migration).Call(cmd.Args().Get(0), cmd.Args().Get(1))
+					if err != nil || len(drifts) == 0 {
+						return err
+					}
 
-							t.AddRow(color.New(color.Bold).Sprint(number), k, strconv.Itoa(version), strconv.Itoa(int(vSource)), strconv.Itoa(int(vCompare)), status, strconv.Itoa(diff))
+					t := newTable()
+					t.AddHeaders("NO", "VERSION", "FILE", "APPLIED AT", "APPLIED BY", "STATUS")
 
-							number++
-						}
+					for i, drift := range drifts {
+						t.AddRow(
+							color.New(color.Bold).Sprint(i+1),
+							strconv.Itoa(int(drift.History.Version)),
+							drift.File,
+							drift.History.AppliedAt.Local().Format("2006-01-02 15:04:05"),
+							drift.History.OsUser,
+							color.New(color.FgRed, color.Bold).Sprint(drift.Status),
+						)
 					}
 
 					t.Render()
 
-					return nil
+					return config.DriftError("%d applied migration file(s) changed or missing", len(drifts))
 				},
 			},
 			{
 				Name:        "inspect",
+				Before:      loadConfig,
 				Aliases:     []string{"d"},
 				Description: "inspect <table> <schema> <connection1> [<connection2> ... --dump]",
 				Usage:       "Inspect <table> on <schema> on <connection1> [<connection2> ... --dump]",
@@ -502,115 +671,42 @@ func main() {
 				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					if cmd.NArg() < 3 {
-						return errors.New("not enough arguments. Usage: kmt inspect <table> <schema> <connection1> [<connection2> ... --dump]")
+						return config.ConfigError("not enough arguments. Usage: kmt inspect <table> <schema> <connection1> [<connection2> ... --dump]")
 					}
 
 					cmdInspect := command.NewInspect(cfg.Migration)
 
-					t := table.New(os.Stdout)
-					t.SetHeaderStyle(table.StyleBold)
-					t.SetLineStyle(table.StyleBrightBlack)
-					t.SetDividers(table.UnicodeRoundedDividers)
-
 					if cmd.NArg() == 3 {
-						columns := cmdInspect.Describe(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Get(2))
-
-						t.AddHeaders("NO", "NAME", "DATA TYPE", "NULL?", "DEFAULT")
-
-						number := 1
-						for k, v := range columns {
-							var status string
-							if v.Nullable {
-								status = color.New(color.FgGreen).Sprint("v")
-							} else {
-								status = color.New(color.FgRed, color.Bold).Sprint("x")
-							}
-
-							t.AddRow(color.New(color.Bold).Sprint(number), color.New(color.Bold).Sprint(k), v.DataType, status, v.DefaultValue)
-
-							number++
+						columns, err := cmdInspect.Describe(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Get(2))
+						if err != nil {
+							return err
 						}
 
-						t.Render()
-
-						return nil
+						return render(cmd.String("output"), columns, func() {
+							describeTable(columns)
+						})
 					}
 
-					columns := cmdInspect.Compare(cmd.Args().Get(0), cmd.Args().Get(1), cmd.Args().Slice()[2:]...)
-					args := cmd.Args().Slice()
-					dbs := args[2:]
-					headers := []string{"NO", "NAME"}
-					subHeaders := []string{"NO", "NAME"}
-					colSpans := []int{1, 1}
-
-					for _, db := range dbs {
-						headers = append(headers, strings.ToUpper(db))
-						subHeaders = append(subHeaders, "DATA TYPE", "NULL?", "DEFAULT")
-						colSpans = append(colSpans, 3)
-					}
-
-					t.SetHeaders(headers...)
-					t.AddHeaders(subHeaders...)
-					t.SetHeaderColSpans(0, colSpans...)
-					t.SetAutoMergeHeaders(true)
-
-					number := 1
-					for columnName, compare := range columns {
-						num := color.New(color.Bold).Sprint(number)
-						name := columnName
-						row := []string{num, name}
-
-						var (
-							first     *db.Column
-							different bool
-						)
-
-						for _, dbName := range dbs {
-							col := compare.Tables[dbName]
-							if col == nil {
-								col = &db.Column{
-									DataType:     color.New(color.FgRed, color.Bold).Sprint("x"),
-									DefaultValue: color.New(color.FgRed, color.Bold).Sprint("x"),
-								}
-							}
-
-							if first == nil {
-								first = col
-							}
-
-							if first.DataType != col.DataType ||
-								first.Nullable != col.Nullable ||
-								first.DefaultValue != col.DefaultValue {
-								different = true
-							}
-
-							status := color.New(color.FgRed, color.Bold).Sprint("x")
-							if col.Nullable {
-								status = color.New(color.FgGreen).Sprint("v")
-							}
-
-							row = append(row, col.DataType, status, col.DefaultValue)
-						}
-
-						if different {
-							row[0] = color.New(color.FgRed, color.Bold).Sprint(number)
-							row[1] = color.New(color.FgRed, color.Bold).Sprint(columnName)
-						}
-
-						t.AddRow(row...)
-
-						number++
+					dbs := cmd.Args().Slice()[2:]
+					columns, err := cmdInspect.Compare(cmd.Args().Get(0), cmd.Args().Get(1), dbs...)
+					if err != nil {
+						return err
 					}
 
-					t.Render()
+					err = render(cmd.String("output"), columns, func() {
+						inspectTable(dbs, columns)
+					})
+					if err != nil {
+						return err
+					}
 
 					if cmd.Bool("dump") {
 						reference := dbs[0]
 						for _, target := range dbs[1:] {
 							var sql string
-							for columnName, compare := range columns {
-								ref := compare.Tables[reference]
-								dst := compare.Tables[target]
+							for _, compare := range columns {
+								ref := compare.Columns[reference]
+								dst := compare.Columns[target]
 								if ref != nil && dst == nil {
 									if sql == "" {
 										sql = fmt.Sprintf("ALTER TABLE %s\n", cmd.Args().Get(0))
@@ -622,11 +718,11 @@ func main() {
 									}
 
 									var defaultValue string
-									if ref.DefaultValue != "" {
-										defaultValue = fmt.Sprintf(" DEFAULT %s", ref.DefaultValue)
+									if ref.Default != "" {
+										defaultValue = fmt.Sprintf(" DEFAULT %s", ref.Default)
 									}
 
-									sql += fmt.Sprintf(db.ADD_COLUMN, columnName, ref.DataType, nullable, defaultValue)
+									sql += fmt.Sprintf(db.ADD_COLUMN, compare.Name, ref.DataType, nullable, defaultValue)
 								}
 
 								if ref == nil && dst != nil {
@@ -634,7 +730,7 @@ func main() {
 										sql = fmt.Sprintf("ALTER TABLE %s\n", cmd.Args().Get(0))
 									}
 
-									sql += fmt.Sprintf(db.REMOVE_COLUMN, columnName)
+									sql += fmt.Sprintf(db.REMOVE_COLUMN, compare.Name)
 								}
 							}
 
@@ -653,8 +749,40 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:        "config",
+				Before:      loadConfig,
+				Description: "config show|validate",
+				Usage:       "Show or validate the effective Kmtfile configuration",
+				Commands: []*cli.Command{
+					{
+						Name:        "show",
+						Description: "show",
+						Usage:       "Print the merged Kmtfile configuration with secrets redacted",
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							format := cmd.String("output")
+							if format == OUTPUT_TABLE {
+								format = OUTPUT_YAML
+							}
+
+							return render(format, config.Redact(cfg), nil)
+						},
+					},
+					{
+						Name:        "validate",
+						Description: "validate",
+						Usage:       "Check the Kmtfile and report every problem with its line and column",
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							config.SuccessColor.Printf("Configuration is valid, %s connection(s) and %s cluster(s) defined\n", config.BoldColor.Sprint(len(cfg.Migration.Connections)), config.BoldColor.Sprint(len(cfg.Migration.Clusters)))
+
+							return nil
+						},
+					},
+				},
+			},
 			{
 				Name:        "test",
+				Before:      loadConfig,
 				Aliases:     []string{"t"},
 				Description: "test",
 				Usage:       "Test kmt configuration",
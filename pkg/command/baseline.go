@@ -0,0 +1,188 @@
+package command
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+	"github.com/ad3n/kmt/v2/pkg/db"
+
+	"github.com/briandowns/spinner"
+)
+
+type (
+	baseline struct {
+		config *config.Migration
+	}
+
+	baselineTarget struct {
+		name       string
+		connection *config.Connection
+		db         *sql.DB
+	}
+)
+
+func NewBaseline(config *config.Migration) *baseline {
+	return &baseline{config: config}
+}
+
+func (b *baseline) Call(source string, schema string, cluster string) error {
+	sourceConfig, ok := b.config.Connections[source]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", source)
+	}
+
+	schemaConfig, ok := sourceConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found on %s", schema, source)
+	}
+
+	names := []string{source}
+	if cluster != "" {
+		members, ok := b.config.Clusters[cluster]
+		if !ok {
+			return config.ConfigError("cluster '%s' isn't defined", cluster)
+		}
+
+		for _, member := range members {
+			if !slices.Contains(names, member) {
+				names = append(names, member)
+			}
+		}
+	}
+
+	migrationFolder := filepath.Join(b.config.Folder, schema)
+	files, err := config.ListMigrations(migrationFolder)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if len(files) > 0 {
+		return config.ConfigError("schema %s already has %d migration file(s), baseline only adopts a schema without migration files", schema, len(files))
+	}
+
+	targets := make([]*baselineTarget, 0, len(names))
+	for _, name := range names {
+		connection, ok := b.config.Connections[name]
+		if !ok {
+			return config.ConfigError("connection '%s' isn't defined", name)
+		}
+
+		if _, ok := connection.Schemas[schema]; !ok {
+			return config.ConfigError("schema '%s' not found on %s", schema, name)
+		}
+
+		conn, err := config.NewConnection(connection)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		lock, err := config.AcquireLock(conn, schema, b.config.LockWaitTimeout)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		version, dirty, err := config.CurrentVersion(conn, schema)
+		if err != nil {
+			return err
+		}
+
+		if version > 0 || dirty {
+			return config.OutOfSyncError("database %s schema %s is already at version %d, baseline only adopts a schema without migrations", name, schema, version)
+		}
+
+		targets = append(targets, &baselineTarget{name: name, connection: connection, db: conn})
+	}
+
+	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
+	progress.Suffix = fmt.Sprintf(" Reading schema %s on %s", config.SuccessColor.Sprint(schema), config.SuccessColor.Sprint(source))
+	progress.Start()
+
+	snapshot, err := db.NewSnapshot(targets[0].db).Take(schema, schemaConfig.Excludes...)
+	if err != nil {
+		progress.Stop()
+
+		return fmt.Errorf("error when reading schema %s on %s: %w", schema, source, err)
+	}
+
+	for _, target := range targets[1:] {
+		progress.Suffix = fmt.Sprintf(" Comparing schema %s on %s and %s", config.SuccessColor.Sprint(schema), config.SuccessColor.Sprint(source), config.SuccessColor.Sprint(target.name))
+
+		live, err := db.NewSnapshot(target.db).Take(schema, schemaConfig.Excludes...)
+		if err != nil {
+			progress.Stop()
+
+			return fmt.Errorf("error when reading schema %s on %s: %w", schema, target.name, err)
+		}
+
+		if db.Diff(snapshot, live).UpScript != "" {
+			progress.Stop()
+
+			return config.DriftError("schema %s on %s differs from %s, run kmt diff %s %s %s and align it before the baseline", schema, target.name, source, source, target.name, schema)
+		}
+	}
+
+	progress.Stop()
+
+	migration := db.Diff(snapshot, db.EmptySnapshot(schema))
+	if migration.UpScript == "" {
+		return config.ConfigError("schema %s on %s has no objects to baseline", schema, source)
+	}
+
+	if err := os.MkdirAll(migrationFolder, 0777); err != nil {
+		return err
+	}
+
+	version := time.Now().Unix()
+	header := fmt.Sprintf("-- Baseline of schema %s on %s\n\n", schema, source)
+	name := fmt.Sprintf("%d_baseline", version)
+	paths, err := config.WriteMigration(migrationFolder, name, header+migration.UpScript, migration.DownScript, b.config.Layout)
+	if err != nil {
+		return err
+	}
+
+	progress.Suffix = fmt.Sprintf(" Replaying baseline of schema %s on a scratch database on %s", config.SuccessColor.Sprint(schema), config.SuccessColor.Sprint(source))
+	progress.Start()
+
+	replayed, err := replay(sourceConfig, source, schema, migrationFolder, uint(version))
+	progress.Stop()
+	if err == nil && db.Diff(snapshot, replayed).UpScript != "" {
+		err = config.DriftError("baseline of schema %s doesn't recreate the schema on %s, it may use objects kmt can't read yet", schema, source)
+	}
+
+	if err != nil {
+		for _, path := range paths {
+			os.Remove(path)
+		}
+
+		return err
+	}
+
+	config.SuccessColor.Printf("Baseline migration created as %s\n", config.BoldColor.Sprint(name))
+
+	for _, target := range targets {
+		migrator, err := config.NewMigrator(target.db, target.connection, schema, migrationFolder)
+		if err != nil {
+			return err
+		}
+		defer migrator.Close()
+
+		if err := migrator.Force(int(version)); err != nil {
+			return config.MigrationError(err)
+		}
+
+		if err := config.RecordBaseline(target.db, schema, migrationFolder, uint(version)); err != nil {
+			return err
+		}
+
+		config.SuccessColor.Printf("Migration on %s schema %s set to baseline %s\n", config.BoldColor.Sprint(target.name), config.BoldColor.Sprint(schema), config.BoldColor.Sprint(version))
+	}
+
+	return nil
+}
@@ -17,42 +17,34 @@ func NewClean(config *config.Migration) *clean {
 func (c *clean) Call(source string, schema string) error {
 	dbConfig, ok := c.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", schema)
-
-		return nil
+		return config.ConfigError("schema '%s' not found", schema)
 	}
 
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer db.Close()
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, filepath.Join(c.config.Folder, schema))
-	defer migrator.Close()
-
-	version, dirty, err := migrator.Version()
+	lock, err := config.AcquireLock(db, schema, c.config.LockWaitTimeout)
 	if err != nil {
 		return err
 	}
+	defer lock.Release()
 
-	if version > 0 && dirty {
-		if err := migrator.Force(int(version)); err != nil {
-			return err
-		}
+	migrator, err := config.NewMigrator(db, dbConfig, schema, filepath.Join(c.config.Folder, schema))
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
 
-		if err := migrator.Steps(-1); err != nil {
-			return err
-		}
+	if _, err := cleanDirty(migrator); err != nil {
+		return err
 	}
 
 	config.SuccessColor.Printf("Migration cleaned on %s schema %s\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
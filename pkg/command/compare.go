@@ -1,100 +1,149 @@
 package command
 
 import (
-	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/ad3n/kmt/v2/pkg/config"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
 )
 
-type compare struct {
-	config *config.Migration
-}
+type (
+	compare struct {
+		config *config.Migration
+	}
+
+	CompareRecord struct {
+		Schema         string `json:"schema" yaml:"schema"`
+		File           uint   `json:"file_version" yaml:"file_version"`
+		Source         string `json:"source" yaml:"source"`
+		SourceVersion  uint   `json:"source_version" yaml:"source_version"`
+		Compare        string `json:"compare" yaml:"compare"`
+		CompareVersion uint   `json:"compare_version" yaml:"compare_version"`
+		Sync           bool   `json:"sync" yaml:"sync"`
+		Diff           int    `json:"diff" yaml:"diff"`
+	}
+)
 
 func NewCompare(config *config.Migration) *compare {
 	return &compare{config: config}
 }
 
-func (c *compare) Call(source string, compare string, schema string) (uint, uint, int) {
+func (c *compare) Records(source string, compare string, schema string) ([]*CompareRecord, error) {
 	dbSource, ok := c.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return 0, 0, 0
+		return nil, config.ConfigError("connection '%s' not found", source)
 	}
 
 	dbCompare, ok := c.config.Connections[compare]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(compare))
+		return nil, config.ConfigError("connection '%s' not found", compare)
+	}
+
+	schemas := []string{}
+	if schema != "" {
+		schemas = append(schemas, schema)
+	} else {
+		for k := range dbSource.Schemas {
+			if _, ok := dbCompare.Schemas[k]; ok {
+				schemas = append(schemas, k)
+			}
+		}
 
-		return 0, 0, 0
+		sort.Strings(schemas)
 	}
 
-	_, ok = dbSource.Schemas[schema]
+	records := []*CompareRecord{}
+	for _, k := range schemas {
+		record, err := c.Call(source, compare, k)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func (c *compare) Call(source string, compare string, schema string) (*CompareRecord, error) {
+	dbSource, ok := c.config.Connections[source]
+	if !ok {
+		return nil, config.ConfigError("connection '%s' not found", source)
+	}
+
+	dbCompare, ok := c.config.Connections[compare]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found on %s\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(source))
+		return nil, config.ConfigError("connection '%s' not found", compare)
+	}
 
-		return 0, 0, 0
+	_, ok = dbSource.Schemas[schema]
+	if !ok {
+		return nil, config.ConfigError("schema '%s' not found on %s", schema, source)
 	}
 
 	_, ok = dbCompare.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found on %s\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(compare))
-
-		return 0, 0, 0
+		return nil, config.ConfigError("schema '%s' not found on %s", schema, compare)
 	}
 
 	connSource, err := config.NewConnection(dbSource)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return 0, 0, 0
+		return nil, err
 	}
 	defer connSource.Close()
 
 	connCompare, err := config.NewConnection(dbCompare)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return 0, 0, 0
+		return nil, err
 	}
 	defer connCompare.Close()
 
 	migrationFolder := filepath.Join(c.config.Folder, schema)
-	sourceMigrator := config.NewMigrator(connSource, dbSource.Name, schema, migrationFolder)
+	sourceMigrator, err := config.NewMigrator(connSource, dbSource, schema, migrationFolder)
+	if err != nil {
+		return nil, err
+	}
 	defer sourceMigrator.Close()
 
-	sourceVersion, _, err := sourceMigrator.Version()
-	if err != nil {
-		config.ErrorColor.Println(err.Error())
+	record := CompareRecord{Schema: schema, Source: source, Compare: compare}
 
-		return 0, 0, 0
+	sourceVersion, _, err := sourceMigrator.Version()
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return nil, err
 	}
 
-	compareMigrator := config.NewMigrator(connCompare, dbCompare.Name, schema, migrationFolder)
+	compareMigrator, err := config.NewMigrator(connCompare, dbCompare, schema, migrationFolder)
+	if err != nil {
+		return nil, err
+	}
 	defer compareMigrator.Close()
 
 	compareVersion, _, err := compareMigrator.Version()
-	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return 0, 0, 0
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return nil, err
 	}
 
-	files, err := os.ReadDir(migrationFolder)
-	if err != nil {
-		config.ErrorColor.Println(err.Error())
+	record.SourceVersion = sourceVersion
+	record.CompareVersion = compareVersion
 
-		return 0, 0, 0
+	files, err := config.ListMigrations(migrationFolder)
+	if err != nil {
+		return nil, err
 	}
 
-	filesLength := len(files)
-	if filesLength == 0 {
-		return sourceVersion, compareVersion, 0
+	if len(files) == 0 {
+		record.Sync = sourceVersion == compareVersion
+
+		return &record, nil
 	}
 
+	record.File = files[len(files)-1].Version
+	record.Sync = record.File == sourceVersion && sourceVersion == compareVersion
+
 	if sourceVersion == compareVersion {
-		return sourceVersion, compareVersion, 0
+		return &record, nil
 	}
 
 	version := sourceVersion
@@ -103,35 +152,9 @@ func (c *compare) Call(source string, compare string, schema string) (uint, uint
 		version, breakPoint = breakPoint, version
 	}
 
-	vFile, err := parseMigrationVersion(files[filesLength-1].Name())
-	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return 0, 0, 0
-	}
-
-	valid := false
 	number := 0
-	for i, file := range files {
-		if i%2 == 0 {
-			continue
-		}
-
-		s, _ := parseMigrationVersion(file.Name())
-		v := uint(s)
-		if v == breakPoint {
-			number++
-
-			break
-		}
-
-		if !valid && (version == v || vFile == s) {
-			valid = true
-
-			continue
-		}
-
-		if valid {
+	for _, file := range files {
+		if file.Version > version && file.Version <= breakPoint {
 			number++
 		}
 	}
@@ -140,5 +163,7 @@ func (c *compare) Call(source string, compare string, schema string) (uint, uint
 		number = number * -1
 	}
 
-	return sourceVersion, compareVersion, number
+	record.Diff = number
+
+	return &record, nil
 }
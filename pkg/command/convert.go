@@ -0,0 +1,76 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+)
+
+type convert struct {
+	config *config.Migration
+}
+
+func NewConvert(config *config.Migration) *convert {
+	return &convert{config: config}
+}
+
+func (c *convert) Call(schema string, layout string) error {
+	if layout != config.LAYOUT_SPLIT && layout != config.LAYOUT_SINGLE {
+		return config.ConfigError("layout %s is not supported, expected %s or %s", layout, config.LAYOUT_SPLIT, config.LAYOUT_SINGLE)
+	}
+
+	migrationFolder := filepath.Join(c.config.Folder, schema)
+	files, err := config.ListMigrations(migrationFolder)
+	if os.IsNotExist(err) {
+		return config.ConfigError("migration folder for schema '%s' not found", schema)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	converted := 0
+	for _, file := range files {
+		if file.Single == (layout == config.LAYOUT_SINGLE) {
+			continue
+		}
+
+		up, err := file.Read(true)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		down, err := file.Read(false)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		paths, err := config.WriteMigration(migrationFolder, fmt.Sprintf("%d_%s", file.Version, file.Name), string(up), string(down), layout)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range slices.Compact([]string{file.Up, file.Down}) {
+			if path != "" && !slices.Contains(paths, path) {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+			}
+		}
+
+		converted++
+	}
+
+	if converted == 0 {
+		config.SuccessColor.Printf("Migrations of schema %s already use the %s layout\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(layout))
+
+		return nil
+	}
+
+	config.SuccessColor.Printf("Converted %d migration(s) of schema %s to the %s layout\n", converted, config.BoldColor.Sprint(schema), config.BoldColor.Sprint(layout))
+
+	return nil
+}
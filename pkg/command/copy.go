@@ -19,67 +19,63 @@ func NewCopy(config *config.Migration) *copy {
 func (c *copy) Call(schema string, source string, destination string) error {
 	sourceConfig, ok := c.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", source)
 	}
 
 	_, ok = sourceConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found on %s\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("schema '%s' not found on %s", schema, source)
 	}
 
 	destinationConfig, ok := c.config.Connections[destination]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(destination))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", destination)
 	}
 
 	_, ok = destinationConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found on %s\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(destination))
-
-		return nil
+		return config.ConfigError("schema '%s' not found on %s", schema, destination)
 	}
 
 	sourceDb, err := config.NewConnection(sourceConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer sourceDb.Close()
 
 	destinationDb, err := config.NewConnection(destinationConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer destinationDb.Close()
 
+	lock, err := config.AcquireLock(destinationDb, schema, c.config.LockWaitTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	migrationFolder := filepath.Join(c.config.Folder, schema)
-	sourceMigrator := config.NewMigrator(sourceDb, sourceConfig.Name, schema, migrationFolder)
+	sourceMigrator, err := config.NewMigrator(sourceDb, sourceConfig, schema, migrationFolder)
+	if err != nil {
+		return err
+	}
 	defer sourceMigrator.Close()
 
-	destinationMigrator := config.NewMigrator(destinationDb, destinationConfig.Name, schema, migrationFolder)
+	destinationMigrator, err := config.NewMigrator(destinationDb, destinationConfig, schema, migrationFolder)
+	if err != nil {
+		return err
+	}
 	defer destinationMigrator.Close()
 
 	sourceVersion, _, err := sourceMigrator.Version()
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 
 	destinationVersion, _, err := destinationMigrator.Version()
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 
 	if destinationVersion > sourceVersion {
@@ -88,15 +84,20 @@ func (c *copy) Call(schema string, source string, destination string) error {
 		return nil
 	}
 
-	if sourceVersion == destinationVersion {
-		config.SuccessColor.Printf("Migration for schema %s on %s has same version with %s\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(destination), config.BoldColor.Sprint(source))
+	if sourceVersion != destinationVersion {
+		err = destinationMigrator.Migrate(sourceVersion)
+		if err != nil && err != gomigrate.ErrNoChange {
+			return config.MigrationError(err)
+		}
+	}
 
-		return nil
+	applied, err := config.ApplyRepeatables(destinationDb, destinationConfig, schema, migrationFolder)
+	if err != nil {
+		return config.MigrationError(err)
 	}
 
-	err = destinationMigrator.Migrate(sourceVersion)
-	if err != nil && err == gomigrate.ErrNoChange {
-		config.SuccessColor.Printf("Database %s schema %s is up to date\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
+	if sourceVersion == destinationVersion && applied == 0 {
+		config.SuccessColor.Printf("Migration for schema %s on %s has same version with %s\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(destination), config.BoldColor.Sprint(source))
 
 		return nil
 	}
@@ -105,3 +106,59 @@ func (c *copy) Call(schema string, source string, destination string) error {
 
 	return nil
 }
+
+func (c *copy) DryRun(schema string, source string, destination string) error {
+	sourceConfig, ok := c.config.Connections[source]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", source)
+	}
+
+	_, ok = sourceConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found on %s", schema, source)
+	}
+
+	destinationConfig, ok := c.config.Connections[destination]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", destination)
+	}
+
+	_, ok = destinationConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found on %s", schema, destination)
+	}
+
+	sourceDb, err := config.NewConnection(sourceConfig)
+	if err != nil {
+		return err
+	}
+	defer sourceDb.Close()
+
+	destinationDb, err := config.NewConnection(destinationConfig)
+	if err != nil {
+		return err
+	}
+	defer destinationDb.Close()
+
+	sourceVersion, _, err := config.CurrentVersion(sourceDb, schema)
+	if err != nil {
+		return err
+	}
+
+	destinationVersion, _, err := config.CurrentVersion(destinationDb, schema)
+	if err != nil {
+		return err
+	}
+
+	if destinationVersion > sourceVersion {
+		config.SuccessColor.Printf("Your schema %s on %s has higher version than %s\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(destination), config.BoldColor.Sprint(source))
+
+		return nil
+	}
+
+	if err := dryRun(destinationDb, destination, destinationConfig, schema, filepath.Join(c.config.Folder, schema), fixedVersion(sourceVersion)); err != nil {
+		return err
+	}
+
+	return dryRunRepeatables(destinationDb, destination, destinationConfig, schema, filepath.Join(c.config.Folder, schema))
+}
@@ -34,9 +34,7 @@ func (c *create) Call(schema string, name string) error {
 	}
 
 	if !valid {
-		config.ErrorColor.Printf("Schema '%s' not found in all connections\n", config.BoldColor.Sprint(schema))
-
-		return nil
+		return config.ConfigError("schema '%s' not found in all connections", schema)
 	}
 
 	version := time.Now().Unix()
@@ -45,18 +43,9 @@ func (c *create) Call(schema string, name string) error {
 	os.MkdirAll(migrationFolder, 0777)
 
 	name = fmt.Sprintf("%d_%s", version, name)
-	_, err := os.Create(filepath.Join(migrationFolder, name+".up.sql"))
-	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
-	}
-
-	_, err = os.Create(filepath.Join(migrationFolder, name+".down.sql"))
+	_, err := config.WriteMigration(migrationFolder, name, "", "", c.config.Layout)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 
 	config.SuccessColor.Printf("Migration created as %s\n", config.BoldColor.Sprint(name))
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+	"github.com/ad3n/kmt/v2/pkg/db"
+
+	"github.com/briandowns/spinner"
+)
+
+type diff struct {
+	config *config.Migration
+}
+
+func NewDiff(config *config.Migration) *diff {
+	return &diff{config: config}
+}
+
+func (d *diff) Call(source string, target string, schema string) error {
+	sourceConfig, ok := d.config.Connections[source]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", source)
+	}
+
+	schemaConfig, ok := sourceConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found on %s", schema, source)
+	}
+
+	targetConfig, ok := d.config.Connections[target]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", target)
+	}
+
+	targetSchema, ok := targetConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found on %s", schema, target)
+	}
+
+	sourceDb, err := config.NewConnection(sourceConfig)
+	if err != nil {
+		return err
+	}
+	defer sourceDb.Close()
+
+	targetDb, err := config.NewConnection(targetConfig)
+	if err != nil {
+		return err
+	}
+	defer targetDb.Close()
+
+	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
+	progress.Suffix = fmt.Sprintf(" Comparing schema %s on %s and %s", config.SuccessColor.Sprint(schema), config.SuccessColor.Sprint(source), config.SuccessColor.Sprint(target))
+	progress.Start()
+
+	sourceSnapshot, err := db.NewSnapshot(sourceDb).Take(schema, schemaConfig.Excludes...)
+	if err != nil {
+		progress.Stop()
+
+		return fmt.Errorf("error when reading schema %s on %s: %w", schema, source, err)
+	}
+
+	targetSnapshot, err := db.NewSnapshot(targetDb).Take(schema, targetSchema.Excludes...)
+	if err != nil {
+		progress.Stop()
+
+		return fmt.Errorf("error when reading schema %s on %s: %w", schema, target, err)
+	}
+
+	migration := db.Diff(sourceSnapshot, targetSnapshot)
+
+	progress.Stop()
+
+	if migration.UpScript == "" {
+		config.SuccessColor.Printf("Schema %s on %s is same with %s\n", config.BoldColor.Sprint(schema), config.BoldColor.Sprint(target), config.BoldColor.Sprint(source))
+
+		return nil
+	}
+
+	migrationFolder := filepath.Join(d.config.Folder, schema)
+	if err := os.MkdirAll(migrationFolder, 0777); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d_diff_%s_%s", time.Now().Unix(), source, target)
+	header := fmt.Sprintf("-- Sync %s -> %s on schema %s\n\n", source, target, schema)
+
+	if _, err := config.WriteMigration(migrationFolder, name, header+migration.UpScript, header+migration.DownScript, d.config.Layout); err != nil {
+		return err
+	}
+
+	config.SuccessColor.Printf("Migration created as %s\n", config.BoldColor.Sprint(name))
+
+	return nil
+}
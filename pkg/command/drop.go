@@ -21,27 +21,30 @@ func NewDrop(config *config.Migration) *drop {
 func (d *drop) Call(source string, schema string) error {
 	dbConfig, ok := d.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", schema)
-
-		return nil
+		return config.ConfigError("schema '%s' not found", schema)
 	}
 
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer db.Close()
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, filepath.Join(d.config.Folder, schema))
+	lock, err := config.AcquireLock(db, schema, d.config.LockWaitTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	migrator, err := config.NewMigrator(db, dbConfig, schema, filepath.Join(d.config.Folder, schema))
+	if err != nil {
+		return err
+	}
 	defer migrator.Close()
 
 	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
@@ -57,19 +60,14 @@ func (d *drop) Call(source string, schema string) error {
 		return nil
 	}
 
-	version, dirty, err := migrator.Version()
 	if err != nil {
-		return err
-	}
+		progress.Stop()
 
-	if version > 0 && dirty {
-		if err := migrator.Force(int(version)); err != nil {
-			return err
-		}
+		return config.MigrationError(err)
+	}
 
-		if err := migrator.Steps(-1); err != nil {
-			return err
-		}
+	if _, err := cleanDirty(migrator); err != nil {
+		return err
 	}
 
 	progress.Stop()
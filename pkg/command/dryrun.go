@@ -0,0 +1,181 @@
+package command
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+)
+
+type (
+	pending struct {
+		File      *config.MigrationFile
+		Path      string
+		Direction string
+	}
+
+	targetResolver func(current uint, files []*config.MigrationFile) (uint, bool)
+)
+
+func latestVersion(current uint, files []*config.MigrationFile) (uint, bool) {
+	if len(files) == 0 {
+		return current, true
+	}
+
+	return files[len(files)-1].Version, true
+}
+
+func stepVersion(step int) targetResolver {
+	return func(current uint, files []*config.MigrationFile) (uint, bool) {
+		target := current
+		for _, file := range files {
+			if step == 0 {
+				break
+			}
+
+			if file.Version > current {
+				target = file.Version
+				step--
+			}
+		}
+
+		return target, true
+	}
+}
+
+func fixedVersion(version uint) targetResolver {
+	return func(current uint, files []*config.MigrationFile) (uint, bool) {
+		if version == 0 {
+			return version, true
+		}
+
+		for _, file := range files {
+			if file.Version == version {
+				return version, true
+			}
+		}
+
+		return version, false
+	}
+}
+
+func (p *pending) up() bool {
+	return p.Direction == "up"
+}
+
+func pendingMigrations(files []*config.MigrationFile, current uint, target uint) []*pending {
+	migrations := []*pending{}
+	if target >= current {
+		for _, file := range files {
+			if file.Version > current && file.Version <= target {
+				migrations = append(migrations, &pending{File: file, Path: file.Up, Direction: "up"})
+			}
+		}
+
+		return migrations
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
+		if file.Version > target && file.Version <= current {
+			migrations = append(migrations, &pending{File: file, Path: file.Down, Direction: "down"})
+		}
+	}
+
+	return migrations
+}
+
+func dryRun(db *sql.DB, source string, connection *config.Connection, schema string, folder string, resolve targetResolver) error {
+	current, dirty, err := config.CurrentVersion(db, schema)
+	if err != nil {
+		return err
+	}
+
+	files, err := config.ListMigrations(folder)
+	if err != nil {
+		return err
+	}
+
+	target, ok := resolve(current, files)
+	if !ok {
+		return config.ConfigError("migration file for version %d not found", target)
+	}
+
+	migrations := pendingMigrations(files, current, target)
+
+	config.BoldColor.Printf("-- Dry run for %s schema %s, version %d -> %d, %d migration(s)\n", source, schema, current, target, len(migrations))
+	if dirty {
+		config.ErrorColor.Printf("-- Database %s schema %s is dirty at version %d, run clean before applying\n", source, schema, current)
+	}
+
+	for i, migration := range migrations {
+		config.BoldColor.Printf("\n-- [%d/%d] %d %s (%s) %s\n", i+1, len(migrations), migration.File.Version, migration.File.Name, migration.Direction, migration.Path)
+
+		if migration.Path == "" {
+			fmt.Printf("-- File for %s migration not found\n", migration.Direction)
+
+			continue
+		}
+
+		body, err := migration.File.Read(migration.up())
+		if err != nil {
+			return err
+		}
+
+		body, err = config.Render(filepath.Base(migration.Path), body, connection.SchemaVars(schema))
+		if err != nil {
+			return err
+		}
+
+		header, err := config.ParseHeader(body)
+		if err != nil {
+			return fmt.Errorf("migration %s %s", filepath.Base(migration.Path), err.Error())
+		}
+
+		if reason := migration.File.Scope(migration.up(), header).Skipped(connection); reason != "" {
+			fmt.Printf("-- File %s, recorded without running\n", reason)
+
+			continue
+		}
+
+		fmt.Println(strings.TrimRight(string(body), "\n"))
+	}
+
+	fmt.Println()
+
+	return nil
+}
+
+func dryRunRepeatables(db *sql.DB, source string, connection *config.Connection, schema string, folder string) error {
+	repeatables, err := config.PendingRepeatables(db, connection, schema, folder)
+	if err != nil || len(repeatables) == 0 {
+		return err
+	}
+
+	config.BoldColor.Printf("-- Dry run for %s schema %s, %d repeatable(s) new or changed\n", source, schema, len(repeatables))
+
+	for i, repeatable := range repeatables {
+		name := filepath.Join(config.REPEATABLE_FOLDER, repeatable.Name)
+		config.BoldColor.Printf("\n-- [%d/%d] %s\n", i+1, len(repeatables), repeatable.Path)
+
+		body := repeatable.Content()
+		header, err := config.ParseHeader(body)
+		if err != nil {
+			return fmt.Errorf("migration %s %s", name, err.Error())
+		}
+
+		if reason := header.Skipped(connection); reason != "" {
+			fmt.Printf("-- File %s, recorded without running\n", reason)
+
+			continue
+		}
+
+		fmt.Println(strings.TrimRight(string(body), "\n"))
+	}
+
+	fmt.Println()
+
+	return nil
+}
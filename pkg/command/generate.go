@@ -2,12 +2,14 @@ package command
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strings"
 	_sync "sync"
 	"time"
 
@@ -24,6 +26,9 @@ type GenerateScope struct {
 	MaterializedViews []string
 	Enums             []string
 	IncludeData       bool
+	PgDump            bool
+	ConcurrentIndex   bool
+	Repeatable        bool
 }
 
 type generate struct {
@@ -31,14 +36,9 @@ type generate struct {
 	config     *config.Migration
 }
 
-type migration struct {
-	wg         *_sync.WaitGroup
-	tableTool  *db.Table
-	folder     string
-	schema     string
-	table      string
-	version    int64
-	schemaOnly bool
+type objects struct {
+	migrations map[db.Node][]*db.Migration
+	tables     map[string]*db.Ddl
 }
 
 func NewGenerate(config *config.Migration, connection *sql.DB) *generate {
@@ -49,162 +49,251 @@ func NewGenerate(config *config.Migration, connection *sql.DB) *generate {
 }
 
 func (g *generate) Call(connection string, schema string, scope *GenerateScope) error {
-	cli := exec.Command(g.config.PgDump, "--version")
-	err := cli.Run()
-	if err != nil {
-		config.ErrorColor.Printf("PG Dump not found in %s\n", config.BoldColor.Sprint(g.config.PgDump))
-
-		return nil
+	if scope.PgDump {
+		cli := exec.Command(g.config.PgDump, "--version")
+		err := cli.Run()
+		if err != nil {
+			return config.ConfigError("pg_dump not found in %s", g.config.PgDump)
+		}
 	}
 
 	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
 
 	source, ok := g.config.Connections[connection]
 	if !ok {
-		config.ErrorColor.Printf("Config for '%s' not found\n", connection)
-
-		return nil
+		return config.ConfigError("config for '%s' not found", connection)
 	}
 
 	schemaConfig, ok := source.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", schema)
+		return config.ConfigError("schema '%s' not found", schema)
+	}
+
+	progress.Suffix = fmt.Sprintf(" Resolving dependencies on schema %s...", config.SuccessColor.Sprint(schema))
+	progress.Start()
+
+	graph, err := db.NewDependency(g.connection).Objects(schema)
+	if err != nil {
+		progress.Stop()
 
-		return nil
+		return err
 	}
 
-	migrationFolder := filepath.Join(g.config.Folder, schema)
-	os.MkdirAll(migrationFolder, 0777)
+	result := &objects{
+		migrations: make(map[db.Node][]*db.Migration),
+		tables:     make(map[string]*db.Ddl),
+	}
 
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing enums on schema %s...", config.SuccessColor.Sprint(schema))
 	progress.Start()
 
-	version := time.Now().Unix()
-
-	version = g.generateEnums(schema, migrationFolder, version, scope.Enums...)
+	g.generateEnums(result, schema, scope.Enums...)
 
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing tables on schema %s...", config.SuccessColor.Sprint(schema))
 	progress.Start()
 
-	version = g.generateTables(connection, schema, schemaConfig, migrationFolder, version, scope)
+	if err := g.generateTables(result, connection, schema, schemaConfig, scope); err != nil {
+		progress.Stop()
+
+		return fmt.Errorf("migration generation on schema %s aborted, %w", schema, err)
+	}
 
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing functions on schema %s...", config.SuccessColor.Sprint(schema))
 	progress.Start()
 
-	version = g.generateFunctions(schema, migrationFolder, version, scope.Functions...)
+	g.generateFunctions(result, schema, scope.Functions...)
 
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing views on schema %s...", config.SuccessColor.Sprint(schema))
 	progress.Start()
 
-	version = g.generateViews(schema, migrationFolder, version, scope.Views...)
+	g.generateViews(result, schema, scope.Views...)
 
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing materialized views on schema %s...", config.SuccessColor.Sprint(schema))
 	progress.Start()
 
-	g.generateMaterializedViews(schema, migrationFolder, version, scope.MaterializedViews...)
+	g.generateMaterializedViews(result, schema, scope.MaterializedViews...)
+
+	nodes := make([]db.Node, 0, len(result.migrations)+len(result.tables))
+	for node := range result.migrations {
+		nodes = append(nodes, node)
+	}
+
+	for table := range result.tables {
+		nodes = append(nodes, db.Node{Kind: db.KIND_TABLE, Name: table})
+	}
+
+	order, err := graph.Subgraph(nodes...).Sort()
+	if err != nil {
+		progress.Stop()
+
+		return fmt.Errorf("migration generation on schema %s aborted, %w", schema, err)
+	}
 
 	progress.Stop()
+	progress.Suffix = fmt.Sprintf(" Writing migrations on schema %s...", config.SuccessColor.Sprint(schema))
+	progress.Start()
 
-	config.SuccessColor.Printf("Migration generation on schema %s run successfully\n", config.BoldColor.Sprint(schema))
+	migrationFolder := filepath.Join(g.config.Folder, schema)
+	os.MkdirAll(migrationFolder, 0777)
 
-	return nil
-}
+	version := time.Now().Unix()
+	tables := make([]string, 0, len(result.tables))
+	for _, node := range order {
+		if scope.Repeatable && (node.Kind == db.KIND_FUNCTION || node.Kind == db.KIND_VIEW) {
+			g.writeRepeatable(migrationFolder, node, result.migrations[node])
 
-func (g *generate) generateEnums(schema string, folder string, version int64, enums ...string) int64 {
-	for _, enum := range enums {
-		udts := db.NewEnum(g.connection).GenerateDdlSingle(schema, enum)
-		for ddl := range udts {
-			g.write(folder, version, "enum", ddl.Name, ddl.UpScript, ddl.DownScript)
+			continue
+		}
 
-			version++
+		if node.Kind != db.KIND_TABLE {
+			for _, ddl := range result.migrations[node] {
+				g.write(migrationFolder, version, node.Kind, ddl.Name, ddl.UpScript, ddl.DownScript)
+
+				version++
+			}
+
+			continue
 		}
-	}
 
-	return version
-}
+		ddl, ok := result.tables[node.Name]
+		if !ok {
+			continue
+		}
 
-func (g *generate) generateFunctions(schema, folder string, version int64, functions ...string) int64 {
-	if len(functions) > 0 && functions[0] == "all" {
-		funcs := db.NewFunction(g.connection).GenerateDdl(schema)
-		for ddl := range funcs {
-			g.write(folder, version, "function", ddl.Name, ddl.UpScript, ddl.DownScript)
+		tables = append(tables, node.Name)
+
+		table := ddl.Definition
+		if scope.ConcurrentIndex {
+			table, _ = ddl.Indexes()
+		}
 
+		g.write(migrationFolder, version, "table", node.Name, table.UpScript, table.DownScript)
+		version++
+
+		if ddl.Reference.UpScript != "" {
+			g.write(migrationFolder, version, "primary_key", node.Name, ddl.Reference.UpScript, ddl.Reference.DownScript)
 			version++
 		}
+	}
 
-		return version
+	for _, table := range tables {
+		if g.writeForeignKey(migrationFolder, result.tables[table], version) {
+			version++
+		}
 	}
 
-	for _, function := range functions {
-		funcs := db.NewFunction(g.connection).GenerateDdlSingle(schema, function)
-		for ddl := range funcs {
-			g.write(folder, version, "function", ddl.Name, ddl.UpScript, ddl.DownScript)
+	for _, table := range g.insertOrder(schema, tables) {
+		if g.writeInsert(migrationFolder, result.tables[table], version) {
+			version++
+		}
+	}
 
+	if scope.ConcurrentIndex {
+		for _, table := range tables {
+			_, index := result.tables[table].Indexes()
+			if index.UpScript == "" {
+				continue
+			}
+
+			index = index.Concurrently()
+			g.write(migrationFolder, version, "index", table, index.UpScript, index.DownScript)
 			version++
 		}
 	}
 
-	return version
+	progress.Stop()
+
+	config.SuccessColor.Printf("Migration generation on schema %s run successfully\n", config.BoldColor.Sprint(schema))
+
+	return nil
 }
 
-func (g *generate) generateViews(schema, folder string, version int64, views ...string) int64 {
-	if len(views) > 0 && views[0] == "all" {
-		lViews := db.NewView(g.connection).GenerateDdl(schema)
-		for ddl := range lViews {
-			g.write(folder, version, "view", ddl.Name, ddl.UpScript, ddl.DownScript)
+func (g *generate) insertOrder(schema string, tables []string) []string {
+	graph, err := db.NewDependency(g.connection).ForeignKeys(schema)
+	if err != nil {
+		config.ErrorColor.Println(err.Error())
 
-			version++
-		}
+		return tables
+	}
 
-		return version
+	nodes := make([]db.Node, 0, len(tables))
+	for _, table := range tables {
+		nodes = append(nodes, db.Node{Kind: db.KIND_TABLE, Name: table})
 	}
 
-	for _, view := range views {
-		lViews := db.NewView(g.connection).GenerateDdlSingle(schema, view)
-		for ddl := range lViews {
-			g.write(folder, version, "view", ddl.Name, ddl.UpScript, ddl.DownScript)
+	order, err := graph.Subgraph(nodes...).Sort()
+	if err != nil {
+		config.ErrorColor.Printf("Insert migrations keep table order, %s\n", err.Error())
 
-			version++
+		return tables
+	}
+
+	sorted := make([]string, 0, len(tables))
+	for _, node := range order {
+		if slices.Contains(tables, node.Name) {
+			sorted = append(sorted, node.Name)
 		}
 	}
 
-	return version
+	return sorted
 }
 
-func (g *generate) generateMaterializedViews(schema, folder string, version int64, mViews ...string) int64 {
-	if len(mViews) > 0 && mViews[0] == "all" {
-		materializedViews := db.NewMaterializedView(g.connection).GenerateDdl(schema)
-		for ddl := range materializedViews {
-			g.write(folder, version, "materialized_view", ddl.Name, ddl.UpScript, ddl.DownScript)
+func (g *generate) generateEnums(result *objects, schema string, enums ...string) {
+	if len(enums) > 0 && enums[0] == "all" {
+		result.add(db.KIND_ENUM, db.NewEnum(g.connection).GenerateDdl(schema))
 
-			version++
-		}
+		return
+	}
 
-		return version
+	for _, enum := range enums {
+		result.add(db.KIND_ENUM, db.NewEnum(g.connection).GenerateDdlSingle(schema, enum))
 	}
+}
 
-	for _, view := range mViews {
-		funcs := db.NewMaterializedView(g.connection).GenerateDdlSingle(schema, view)
-		for ddl := range funcs {
-			g.write(folder, version, "materialized_view", ddl.Name, ddl.UpScript, ddl.DownScript)
+func (g *generate) generateFunctions(result *objects, schema string, functions ...string) {
+	if len(functions) > 0 && functions[0] == "all" {
+		result.add(db.KIND_FUNCTION, db.NewFunction(g.connection).GenerateDdl(schema))
 
-			version++
-		}
+		return
 	}
 
-	return version
+	for _, function := range functions {
+		result.add(db.KIND_FUNCTION, db.NewFunction(g.connection).GenerateDdlSingle(schema, function))
+	}
 }
 
-func (g *generate) getTables(worker int, schema string, table []string, excludes ...string) (<-chan string, int) {
-	if len(table) > 0 && table[0] == "all" {
-		schemaTool := db.NewSchema(g.connection)
+func (g *generate) generateViews(result *objects, schema string, views ...string) {
+	if len(views) > 0 && views[0] == "all" {
+		result.add(db.KIND_VIEW, db.NewView(g.connection).GenerateDdl(schema))
+
+		return
+	}
 
-		return schemaTool.ListTable(worker, schema, excludes...), schemaTool.CountTable(schema, len(excludes))
+	for _, view := range views {
+		result.add(db.KIND_VIEW, db.NewView(g.connection).GenerateDdlSingle(schema, view))
+	}
+}
+
+func (g *generate) generateMaterializedViews(result *objects, schema string, mViews ...string) {
+	if len(mViews) > 0 && mViews[0] == "all" {
+		result.add(db.KIND_MATERIALIZED_VIEW, db.NewMaterializedView(g.connection).GenerateDdl(schema))
+
+		return
+	}
+
+	for _, view := range mViews {
+		result.add(db.KIND_MATERIALIZED_VIEW, db.NewMaterializedView(g.connection).GenerateDdlSingle(schema, view))
+	}
+}
+
+func (g *generate) getTables(worker int, schema string, table []string, excludes ...string) <-chan string {
+	if len(table) > 0 && table[0] == "all" {
+		return db.NewSchema(g.connection).ListTable(worker, schema, excludes...)
 	}
 
 	cTable := make(chan string)
@@ -216,117 +305,89 @@ func (g *generate) getTables(worker int, schema string, table []string, excludes
 		close(cTable)
 	}()
 
-	return cTable, len(table)
+	return cTable
 }
 
 func (g *generate) generateTables(
+	result *objects,
 	connection string,
 	schema string,
-	schemaConfig map[string][]string,
-	folder string,
-	version int64,
+	schemaConfig *config.Schema,
 	scope *GenerateScope,
-) int64 {
+) error {
 	nWorker := runtime.NumCPU()
-	cTable, tTable := g.getTables(nWorker, schema, scope.Tables, schemaConfig["excludes"]...)
-	ddlTool := db.NewTable(g.config.PgDump, g.config.Connections[connection], g.connection)
-	cDdl := make(chan *db.Ddl, nWorker)
-	cInsert := make(chan *db.Ddl, nWorker)
-	cMigration := make(chan *migration, nWorker)
+	cTable := g.getTables(nWorker, schema, scope.Tables, schemaConfig.Excludes...)
+
+	var ddlTool db.Generator = db.NewCatalog(g.connection)
+	if scope.PgDump {
+		ddlTool = db.NewTable(g.config.PgDump, g.config.Connections[connection], g.connection)
+	}
 
 	var wg _sync.WaitGroup
+	var mutex _sync.Mutex
+	var failure error
 
 	for range nWorker {
-		go g.do(cMigration, cDdl)
-	}
-
-	for tableName := range cTable {
 		wg.Add(1)
 
-		schemaOnly := true
-		if slices.Contains(schemaConfig["with_data"], tableName) {
-			schemaOnly = false
-		}
-
-		scope.IncludeData = !schemaOnly
+		go func() {
+			defer wg.Done()
 
-		cMigration <- &migration{
-			wg:         &wg,
-			tableTool:  ddlTool,
-			folder:     folder,
-			version:    version,
-			schema:     schema,
-			table:      tableName,
-			schemaOnly: schemaOnly,
-		}
+			for tableName := range cTable {
+				schemaOnly := !scope.IncludeData && !slices.Contains(schemaConfig.WithData, tableName)
+				script, err := ddlTool.Generate(fmt.Sprintf("%s.%s", schema, tableName), schemaOnly)
 
-		version += 2
+				mutex.Lock()
+				if err != nil {
+					failure = errors.Join(failure, fmt.Errorf("table %s, %w", tableName, err))
+				} else {
+					result.tables[tableName] = script
+				}
+				mutex.Unlock()
+			}
+		}()
 	}
 
-	close(cMigration)
-
-	version += int64(tTable*2) + 1
-	go func(version int64) {
-		defer close(cInsert)
-
-		for ddl := range cDdl {
-			cInsert <- ddl
-
-			g.writeForeignKey(folder, ddl, version)
-
-			version++
-		}
-	}(version)
+	wg.Wait()
 
-	version += int64(tTable) + 1
-	go func(version int64) {
-		for ddl := range cInsert {
-			if scope.IncludeData {
-				g.writeInsert(folder, ddl, version)
+	return failure
+}
 
-				version++
-			}
-		}
-	}(version)
+func (o *objects) add(kind string, migrations <-chan *db.Migration) {
+	for ddl := range migrations {
+		node := db.Node{Kind: kind, Name: ddl.Name}
+		o.migrations[node] = append(o.migrations[node], ddl)
+	}
+}
 
-	wg.Wait()
+func (g *generate) writeRepeatable(folder string, node db.Node, ddls []*db.Migration) {
+	scripts := make([]string, 0, len(ddls))
+	for _, ddl := range ddls {
+		scripts = append(scripts, strings.TrimRight(ddl.UpScript, "\n"))
+	}
 
-	return version + 1
+	os.MkdirAll(filepath.Join(folder, config.REPEATABLE_FOLDER), 0777)
+	os.WriteFile(filepath.Join(folder, config.REPEATABLE_FOLDER, fmt.Sprintf("%s_%s.sql", node.Kind, node.Name)), []byte(strings.Join(scripts, "\n\n")+"\n"), 0666)
 }
 
-func (g *generate) writeForeignKey(folder string, ddl *db.Ddl, version int64) {
+func (g *generate) writeForeignKey(folder string, ddl *db.Ddl, version int64) bool {
 	if ddl.ForeignKey.UpScript == "" {
-		return
+		return false
 	}
 
 	g.write(folder, version, "foreign_key", ddl.Name, ddl.ForeignKey.UpScript, ddl.ForeignKey.DownScript)
+
+	return true
 }
 
-func (g *generate) writeInsert(folder string, ddl *db.Ddl, version int64) {
+func (g *generate) writeInsert(folder string, ddl *db.Ddl, version int64) bool {
 	if ddl.Insert.UpScript == "" {
-		return
+		return false
 	}
 
 	g.write(folder, version, "insert", ddl.Name, ddl.Insert.UpScript, ddl.Insert.DownScript)
-}
-
-func (g *generate) do(cMigration <-chan *migration, cDdl chan<- *db.Ddl) {
-	for m := range cMigration {
-		func(m *migration) {
-			defer m.wg.Done()
-
-			script := m.tableTool.Generate(fmt.Sprintf("%s.%s", m.schema, m.table), m.schemaOnly)
 
-			cDdl <- script
-
-			g.write(m.folder, m.version, "table", m.table, script.Definition.UpScript, script.Definition.DownScript)
-			if script.Reference.UpScript != "" {
-				m.version = m.version + 1
-
-				g.write(m.folder, m.version, "primary_key", m.table, script.Reference.UpScript, script.Reference.DownScript)
-			}
-		}(m)
-	}
+	return true
 }
 
 func (g *generate) write(
@@ -337,21 +398,5 @@ func (g *generate) write(
 	upScript string,
 	downScript string,
 ) {
-	os.WriteFile(
-		filepath.Join(
-			folder,
-			fmt.Sprintf("%d_%s_%s.up.sql", version, objectType, name),
-		),
-		[]byte(upScript),
-		0777,
-	)
-
-	os.WriteFile(
-		filepath.Join(
-			folder,
-			fmt.Sprintf("%d_%s_%s.down.sql", version, objectType, name),
-		),
-		[]byte(downScript),
-		0777,
-	)
+	config.WriteMigration(folder, fmt.Sprintf("%d_%s_%s", version, objectType, name), upScript, downScript, g.config.Layout)
 }
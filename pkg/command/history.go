@@ -0,0 +1,42 @@
+package command
+
+import (
+	"github.com/ad3n/kmt/v2/pkg/config"
+)
+
+type history struct {
+	config *config.Migration
+}
+
+func NewHistory(config *config.Migration) *history {
+	return &history{config: config}
+}
+
+func (h *history) Call(source string, schema string) ([]*config.History, error) {
+	dbConfig, ok := h.config.Connections[source]
+	if !ok {
+		return nil, config.ConfigError("database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return nil, config.ConfigError("schema '%s' not found", schema)
+	}
+
+	db, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	histories, err := config.Histories(db, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(histories) == 0 {
+		config.SuccessColor.Printf("No migration history on %s schema %s\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
+	}
+
+	return histories, nil
+}
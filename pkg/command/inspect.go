@@ -1,84 +1,124 @@
 package command
 
 import (
+	"sort"
+
 	"github.com/ad3n/kmt/v2/pkg/config"
 	"github.com/ad3n/kmt/v2/pkg/db"
 )
 
-type inspect struct {
-	config *config.Migration
-}
+type (
+	inspect struct {
+		config *config.Migration
+	}
+
+	ColumnRecord struct {
+		Connection string `json:"connection" yaml:"connection"`
+		Name       string `json:"name" yaml:"name"`
+		DataType   string `json:"data_type" yaml:"data_type"`
+		Nullable   bool   `json:"nullable" yaml:"nullable"`
+		Default    string `json:"default" yaml:"default"`
+	}
+
+	InspectRecord struct {
+		Name      string                   `json:"name" yaml:"name"`
+		Different bool                     `json:"different" yaml:"different"`
+		Columns   map[string]*ColumnRecord `json:"connections" yaml:"connections"`
+	}
+)
 
 func NewInspect(config *config.Migration) *inspect {
 	return &inspect{config: config}
 }
 
-func (i *inspect) Describe(table string, schema string, connection string) map[string]*db.Column {
+func (i *inspect) Describe(table string, schema string, connection string) ([]*ColumnRecord, error) {
 	cfg, ok := i.config.Connections[connection]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(connection))
-
-		return nil
+		return nil, config.ConfigError("database connection '%s' not found", connection)
 	}
 
 	conn, err := config.NewConnection(cfg)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	defer conn.Close()
 
-	result, err := db.NewTable("", cfg, conn).Detail(table)
+	detail, err := db.NewTable("", cfg, conn).Detail(table)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
+		return nil, err
+	}
 
-		return nil
+	records := make([]*ColumnRecord, 0, len(detail))
+	for _, col := range detail {
+		records = append(records, &ColumnRecord{
+			Connection: connection,
+			Name:       col.Name,
+			DataType:   col.DataType,
+			Nullable:   col.Nullable,
+			Default:    col.DefaultValue,
+		})
 	}
 
-	return result
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+
+	return records, nil
 }
 
-func (i *inspect) Compare(table string, schema string, dbs ...string) map[string]*db.Inspect {
-	compare := make(map[string]*db.Inspect)
+func (i *inspect) Compare(table string, schema string, dbs ...string) ([]*InspectRecord, error) {
+	compare := make(map[string]*InspectRecord)
 
 	for _, dbName := range dbs {
-		cfg, ok := i.config.Connections[dbName]
-		if !ok {
-			config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(dbName))
-
-			continue
+		columns, err := i.Describe(table, schema, dbName)
+		if err != nil {
+			return nil, err
 		}
 
-		conn, err := config.NewConnection(cfg)
-		if err != nil {
-			continue
+		for _, col := range columns {
+			cmp, ok := compare[col.Name]
+			if !ok {
+				cmp = &InspectRecord{
+					Name:    col.Name,
+					Columns: make(map[string]*ColumnRecord),
+				}
+				compare[col.Name] = cmp
+			}
+
+			cmp.Columns[dbName] = col
 		}
+	}
 
-		func() {
-			defer conn.Close()
+	records := make([]*InspectRecord, 0, len(compare))
+	for _, cmp := range compare {
+		var first *ColumnRecord
+		for n, dbName := range dbs {
+			col := cmp.Columns[dbName]
+			if col == nil {
+				cmp.Different = true
 
-			detail, err := db.NewTable("", cfg, conn).Detail(table)
-			if err != nil {
-				config.ErrorColor.Println(err.Error())
-				return
+				break
 			}
 
-			for colName, col := range detail {
-				cmp, ok := compare[colName]
-				if !ok {
-					cmp = &db.Inspect{
-						Tables: make(map[string]*db.Column),
-					}
-					compare[colName] = cmp
-				}
+			if n == 0 {
+				first = col
 
-				cmp.Tables[dbName] = &db.Column{
-					DataType:     col.DataType,
-					DefaultValue: col.DefaultValue,
-					Nullable:     col.Nullable,
-				}
+				continue
+			}
+
+			if first.DataType != col.DataType ||
+				first.Nullable != col.Nullable ||
+				first.Default != col.Default {
+				cmp.Different = true
 			}
-		}()
+		}
+
+		records = append(records, cmp)
 	}
 
-	return compare
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+
+	return records, nil
 }
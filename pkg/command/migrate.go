@@ -1,10 +1,11 @@
 package command
 
 import (
-	"os"
 	"path/filepath"
 
 	"github.com/ad3n/kmt/v2/pkg/config"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
 )
 
 type migrate struct {
@@ -17,23 +18,18 @@ func NewMigrate(config *config.Migration) *migrate {
 
 func (m *migrate) Call(source string, schema string, version int) error {
 	if version <= 0 {
-		config.ErrorColor.Println("Invalid version")
-
-		return nil
+		return config.ConfigError("invalid version")
 	}
 
 	migrationFolder := filepath.Join(m.config.Folder, schema)
-	files, err := os.ReadDir(migrationFolder)
+	files, err := config.ListMigrations(migrationFolder)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 
 	valid := false
 	for _, file := range files {
-		s, _ := parseMigrationVersion(file.Name())
-		if version == s {
+		if uint(version) == file.Version {
 			valid = true
 
 			break
@@ -41,39 +37,56 @@ func (m *migrate) Call(source string, schema string, version int) error {
 	}
 
 	if !valid {
-		config.ErrorColor.Printf("Migration file for version %s not found\n", config.BoldColor.Sprint(version))
-
-		return nil
+		return config.ConfigError("migration file for version %d not found", version)
 	}
 
 	dbConfig, ok := m.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", config.BoldColor.Sprint(schema))
-
-		return nil
+		return config.ConfigError("schema '%s' not found", schema)
 	}
 
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer db.Close()
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, migrationFolder)
+	lock, err := config.AcquireLock(db, schema, m.config.LockWaitTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	migrator, err := config.NewMigrator(db, dbConfig, schema, migrationFolder)
+	if err != nil {
+		return err
+	}
 	defer migrator.Close()
 
+	current, _, _ := migrator.Version()
+
 	err = migrator.Migrate(uint(version))
-	if err != nil {
-		config.ErrorColor.Println(err.Error())
+	if err != nil && err != gomigrate.ErrNoChange {
+		return config.MigrationError(err)
+	}
+
+	changed := err == nil
+	if uint(version) >= current {
+		applied, err := config.ApplyRepeatables(db, dbConfig, schema, migrationFolder)
+		if err != nil {
+			return config.MigrationError(err)
+		}
+
+		changed = changed || applied > 0
+	}
+
+	if !changed {
+		config.SuccessColor.Printf("Database %s schema %s is already at version %s\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema), config.BoldColor.Sprint(version))
 
 		return nil
 	}
@@ -82,3 +95,40 @@ func (m *migrate) Call(source string, schema string, version int) error {
 
 	return nil
 }
+
+func (m *migrate) DryRun(source string, schema string, version int) error {
+	if version <= 0 {
+		return config.ConfigError("invalid version")
+	}
+
+	dbConfig, ok := m.config.Connections[source]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found", schema)
+	}
+
+	db, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	current, _, err := config.CurrentVersion(db, schema)
+	if err != nil {
+		return err
+	}
+
+	if err := dryRun(db, source, dbConfig, schema, filepath.Join(m.config.Folder, schema), fixedVersion(uint(version))); err != nil {
+		return err
+	}
+
+	if uint(version) < current {
+		return nil
+	}
+
+	return dryRunRepeatables(db, source, dbConfig, schema, filepath.Join(m.config.Folder, schema))
+}
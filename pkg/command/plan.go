@@ -0,0 +1,286 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+
+	"github.com/briandowns/spinner"
+	gomigrate "github.com/golang-migrate/migrate/v4"
+)
+
+type (
+	plan struct {
+		config *config.Migration
+	}
+
+	apply struct {
+		config *config.Migration
+	}
+
+	Plan struct {
+		Connection     string           `json:"connection"`
+		Schema         string           `json:"schema"`
+		CurrentVersion uint             `json:"current_version"`
+		TargetVersion  uint             `json:"target_version"`
+		KmtVersion     string           `json:"kmt_version"`
+		CreatedAt      time.Time        `json:"created_at"`
+		Migrations     []*PlanMigration `json:"migrations"`
+		Repeatables    []*PlanMigration `json:"repeatables,omitempty"`
+	}
+
+	PlanMigration struct {
+		Version  uint   `json:"version"`
+		Name     string `json:"name"`
+		File     string `json:"file"`
+		Checksum string `json:"checksum"`
+	}
+)
+
+func NewPlan(config *config.Migration) *plan {
+	return &plan{config: config}
+}
+
+func NewApply(config *config.Migration) *apply {
+	return &apply{config: config}
+}
+
+func (p *plan) Call(source string, schema string, output string) error {
+	dbConfig, ok := p.config.Connections[source]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found", schema)
+	}
+
+	db, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	current, dirty, err := config.CurrentVersion(db, schema)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return config.DirtyError("database %s schema %s is dirty at version %d, run clean before planning", source, schema, current)
+	}
+
+	files, err := config.ListMigrations(filepath.Join(p.config.Folder, schema))
+	if err != nil {
+		return err
+	}
+
+	repeatables, err := config.PendingRepeatables(db, dbConfig, schema, filepath.Join(p.config.Folder, schema))
+	if err != nil {
+		return err
+	}
+
+	target, _ := latestVersion(current, files)
+	migrations := pendingMigrations(files, current, target)
+	if len(migrations) == 0 && len(repeatables) == 0 {
+		config.SuccessColor.Printf("Database %s schema %s is up to date\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
+
+		return nil
+	}
+
+	result := Plan{
+		Connection:     source,
+		Schema:         schema,
+		CurrentVersion: current,
+		TargetVersion:  target,
+		KmtVersion:     config.VERSION_STRING,
+		CreatedAt:      time.Now(),
+		Migrations:     make([]*PlanMigration, 0, len(migrations)),
+	}
+
+	for _, migration := range migrations {
+		checksum, err := migration.File.RenderedChecksum(migration.up(), dbConfig, schema)
+		if err != nil {
+			return err
+		}
+
+		result.Migrations = append(result.Migrations, &PlanMigration{
+			Version:  migration.File.Version,
+			Name:     migration.File.Name,
+			File:     filepath.Base(migration.Path),
+			Checksum: checksum,
+		})
+	}
+
+	for _, repeatable := range repeatables {
+		result.Repeatables = append(result.Repeatables, &PlanMigration{
+			Name:     repeatable.Name,
+			File:     filepath.Join(config.REPEATABLE_FOLDER, repeatable.Name),
+			Checksum: repeatable.Checksum,
+		})
+	}
+
+	content, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(output, append(content, '\n'), 0644)
+	if err != nil {
+		return err
+	}
+
+	config.SuccessColor.Printf(
+		"Plan for %s schema %s saved to %s, version %s -> %s with %s migration(s) and %s repeatable(s)\n",
+		config.BoldColor.Sprint(source),
+		config.BoldColor.Sprint(schema),
+		config.BoldColor.Sprint(output),
+		config.BoldColor.Sprint(current),
+		config.BoldColor.Sprint(target),
+		config.BoldColor.Sprint(len(result.Migrations)),
+		config.BoldColor.Sprint(len(result.Repeatables)),
+	)
+
+	return nil
+}
+
+func (a *apply) Call(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	saved := Plan{}
+	if err := json.Unmarshal(content, &saved); err != nil {
+		return config.ConfigError("invalid plan file %s: %s", path, err.Error())
+	}
+
+	dbConfig, ok := a.config.Connections[saved.Connection]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", saved.Connection)
+	}
+
+	_, ok = dbConfig.Schemas[saved.Schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found", saved.Schema)
+	}
+
+	db, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	lock, err := config.AcquireLock(db, saved.Schema, a.config.LockWaitTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	current, dirty, err := config.CurrentVersion(db, saved.Schema)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return config.DirtyError("plan refused, database %s schema %s is dirty at version %d", saved.Connection, saved.Schema, current)
+	}
+
+	if current != saved.CurrentVersion {
+		return config.OutOfSyncError("plan refused, database %s schema %s is at version %d but the plan was made at version %d", saved.Connection, saved.Schema, current, saved.CurrentVersion)
+	}
+
+	migrationFolder := filepath.Join(a.config.Folder, saved.Schema)
+	files, err := config.ListMigrations(migrationFolder)
+	if err != nil {
+		return err
+	}
+
+	migrations := pendingMigrations(files, current, saved.TargetVersion)
+	if len(migrations) != len(saved.Migrations) {
+		return config.DriftError("plan refused, expected %d migration(s) but found %d on disk", len(saved.Migrations), len(migrations))
+	}
+
+	for i, migration := range migrations {
+		planned := saved.Migrations[i]
+		if migration.File.Version != planned.Version || filepath.Base(migration.Path) != planned.File {
+			return config.DriftError("plan refused, expected %s but found %s", planned.File, filepath.Base(migration.Path))
+		}
+
+		checksum, err := migration.File.RenderedChecksum(migration.up(), dbConfig, saved.Schema)
+		if err != nil {
+			return err
+		}
+
+		if checksum != planned.Checksum {
+			return config.DriftError("plan refused, file %s or its vars changed since the plan was made", planned.File)
+		}
+	}
+
+	repeatables, err := config.PendingRepeatables(db, dbConfig, saved.Schema, migrationFolder)
+	if err != nil {
+		return err
+	}
+
+	if len(repeatables) != len(saved.Repeatables) {
+		return config.DriftError("plan refused, expected %d repeatable(s) but found %d new or changed", len(saved.Repeatables), len(repeatables))
+	}
+
+	for i, repeatable := range repeatables {
+		planned := saved.Repeatables[i]
+		if repeatable.Name != planned.Name || repeatable.Checksum != planned.Checksum {
+			return config.DriftError("plan refused, repeatable %s or its vars changed since the plan was made", planned.File)
+		}
+	}
+
+	_, err = db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", saved.Schema))
+	if err != nil {
+		return err
+	}
+
+	migrator, err := config.NewMigrator(db, dbConfig, saved.Schema, migrationFolder)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if len(saved.Migrations) > 0 {
+		progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
+		progress.Suffix = fmt.Sprintf(" Applying plan for %s on %s schema", config.SuccessColor.Sprint(saved.Connection), config.SuccessColor.Sprint(saved.Schema))
+		progress.Start()
+
+		err = migrator.Migrate(saved.TargetVersion)
+		if err != nil && err != gomigrate.ErrNoChange {
+			progress.Stop()
+
+			return config.MigrationError(err)
+		}
+
+		if _, err := cleanDirty(migrator); err != nil {
+			progress.Stop()
+
+			return err
+		}
+
+		progress.Stop()
+	}
+
+	if _, err := config.ApplyRepeatables(db, dbConfig, saved.Schema, migrationFolder); err != nil {
+		return config.MigrationError(err)
+	}
+
+	config.SuccessColor.Printf(
+		"Plan applied on %s schema %s, version %s -> %s\n",
+		config.BoldColor.Sprint(saved.Connection),
+		config.BoldColor.Sprint(saved.Schema),
+		config.BoldColor.Sprint(saved.CurrentVersion),
+		config.BoldColor.Sprint(saved.TargetVersion),
+	)
+
+	return nil
+}
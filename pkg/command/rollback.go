@@ -16,56 +16,45 @@ func NewRollback(config *config.Migration) *rollback {
 
 func (r *rollback) Call(source string, schema string, step int) error {
 	if step <= 0 {
-		config.ErrorColor.Println("Invalid step")
-
-		return nil
+		return config.ConfigError("invalid step")
 	}
 
 	dbConfig, ok := r.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", config.BoldColor.Sprint(schema))
-
-		return nil
+		return config.ConfigError("schema '%s' not found", schema)
 	}
 
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer db.Close()
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, filepath.Join(r.config.Folder, schema))
-	defer migrator.Close()
-
-	err = migrator.Steps(step * -1)
+	lock, err := config.AcquireLock(db, schema, r.config.LockWaitTimeout)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
+	defer lock.Release()
 
-	version, dirty, err := migrator.Version()
+	migrator, err := config.NewMigrator(db, dbConfig, schema, filepath.Join(r.config.Folder, schema))
 	if err != nil {
 		return err
 	}
+	defer migrator.Close()
 
-	if version > 0 && dirty {
-		if err := migrator.Force(int(version)); err != nil {
-			return err
-		}
+	err = migrator.Steps(step * -1)
+	if err != nil {
+		return config.MigrationError(err)
+	}
 
-		if err := migrator.Steps(-1); err != nil {
-			return err
-		}
+	version, err := cleanDirty(migrator)
+	if err != nil {
+		return err
 	}
 
 	config.SuccessColor.Printf("Migration rolled back to %s on %s schema %s\n", config.BoldColor.Sprint(version), config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
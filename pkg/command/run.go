@@ -2,7 +2,6 @@ package command
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strconv"
 
@@ -21,71 +20,51 @@ func NewRun(config *config.Migration) *run {
 
 func (r *run) Call(source string, schema string, step int) error {
 	if step <= 0 {
-		config.ErrorColor.Println("Invalid step")
-
-		return nil
+		return config.ConfigError("invalid step")
 	}
 
 	dbConfig, ok := r.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", config.BoldColor.Sprint(schema))
-
-		return nil
+		return config.ConfigError("schema '%s' not found", schema)
 	}
 
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer db.Close()
 
+	lock, err := config.AcquireLock(db, schema, r.config.LockWaitTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	migrationFolder := filepath.Join(r.config.Folder, schema)
-	files, err := os.ReadDir(migrationFolder)
+	files, err := config.ListMigrations(migrationFolder)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
+		return err
+	}
 
-		return nil
+	migrator, err := config.NewMigrator(db, dbConfig, schema, migrationFolder)
+	if err != nil {
+		return err
 	}
+	defer migrator.Close()
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, migrationFolder)
 	version, _, _ := migrator.Version()
-	valid := false
-	migrations := make([]string, 0, len(files)/2)
-	number := 0
-	for i, file := range files {
-		if i%2 == 0 {
-			continue
-		}
-
-		s, _ := parseMigrationVersion(file.Name())
-		if !valid && version == uint(s) {
-			valid = true
-
-			continue
-		}
-
-		if valid && number < step {
-			migrations = append(migrations, strconv.Itoa(s))
-
-			number++
+	migrations := make([]string, 0, step)
+	for _, file := range files {
+		if file.Version > version && len(migrations) < step {
+			migrations = append(migrations, strconv.Itoa(int(file.Version)))
 		}
 	}
 
-	if len(migrations) == 0 {
-		config.SuccessColor.Printf("Database %s schema %s is up to date\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
-
-		return nil
-	}
-
 	for _, v := range migrations {
 		progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
 		progress.Suffix = fmt.Sprintf(" Run migration file %s on schema %s", config.SuccessColor.Sprint(v), config.BoldColor.Sprint(schema))
@@ -93,15 +72,53 @@ func (r *run) Call(source string, schema string, step int) error {
 		err = migrator.Steps(1)
 		if err != nil {
 			progress.Stop()
-			config.ErrorColor.Printf("Error when running %s with message %s\n", config.SuccessColor.Sprint(v), config.BoldColor.Sprint(err.Error()))
 
-			return nil
+			return config.MigrationError(fmt.Errorf("error when running %s: %w", v, err))
 		}
 
 		progress.Stop()
 	}
 
+	applied, err := config.ApplyRepeatables(db, dbConfig, schema, migrationFolder)
+	if err != nil {
+		return config.MigrationError(err)
+	}
+
+	if len(migrations) == 0 && applied == 0 {
+		config.SuccessColor.Printf("Database %s schema %s is up to date\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
+
+		return nil
+	}
+
 	config.SuccessColor.Printf("Migration on %s schema %s run successfully\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
 
 	return nil
 }
+
+func (r *run) DryRun(source string, schema string, step int) error {
+	if step <= 0 {
+		return config.ConfigError("invalid step")
+	}
+
+	dbConfig, ok := r.config.Connections[source]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found", schema)
+	}
+
+	db, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := dryRun(db, source, dbConfig, schema, filepath.Join(r.config.Folder, schema), stepVersion(step)); err != nil {
+		return err
+	}
+
+	return dryRunRepeatables(db, source, dbConfig, schema, filepath.Join(r.config.Folder, schema))
+}
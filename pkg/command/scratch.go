@@ -0,0 +1,56 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+	"github.com/ad3n/kmt/v2/pkg/db"
+)
+
+// replay applies the migrations of schema up to a version on a scratch database next to connection and returns the resulting schema.
+func replay(connection *config.Connection, source string, schema string, folder string, until uint) (*db.Snapshot, error) {
+	admin, err := config.NewConnection(connection)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+
+	scratch := fmt.Sprintf("kmt_scratch_%d", time.Now().Unix())
+	if _, err := admin.Exec(fmt.Sprintf("CREATE DATABASE %s", scratch)); err != nil {
+		return nil, fmt.Errorf("error when creating scratch database %s on %s: %w", scratch, source, err)
+	}
+	defer func() {
+		if _, err := admin.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", scratch)); err != nil {
+			config.ErrorColor.Printf("Scratch database %s on %s could not be dropped, %s\n", scratch, source, err.Error())
+		}
+	}()
+
+	scratchConfig := connection.WithDatabase(scratch)
+	conn, err := config.NewConnection(scratchConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", schema)); err != nil {
+		return nil, err
+	}
+
+	migrator, err := config.NewMigrator(conn, scratchConfig, schema, folder)
+	if err != nil {
+		return nil, err
+	}
+	defer migrator.Close()
+
+	if err := migrator.Migrate(until); err != nil {
+		return nil, config.MigrationError(err)
+	}
+
+	snapshot, err := db.NewSnapshot(conn).Take(schema, config.TrackingTables...)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading schema %s on scratch database %s: %w", schema, scratch, err)
+	}
+
+	return snapshot, nil
+}
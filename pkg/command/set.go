@@ -1,7 +1,6 @@
 package command
 
 import (
-	"os"
 	"path/filepath"
 
 	"github.com/ad3n/kmt/v2/pkg/config"
@@ -17,23 +16,18 @@ func NewSet(config *config.Migration) *set {
 
 func (s *set) Call(source string, schema string, version int) error {
 	if version <= 0 {
-		config.ErrorColor.Println("Invalid version")
-
-		return nil
+		return config.ConfigError("invalid version")
 	}
 
 	migrationFolder := filepath.Join(s.config.Folder, schema)
-	files, err := os.ReadDir(migrationFolder)
+	files, err := config.ListMigrations(migrationFolder)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 
 	valid := false
 	for _, file := range files {
-		s, _ := parseMigrationVersion(file.Name())
-		if version == s {
+		if uint(version) == file.Version {
 			valid = true
 
 			break
@@ -41,41 +35,45 @@ func (s *set) Call(source string, schema string, version int) error {
 	}
 
 	if !valid {
-		config.ErrorColor.Printf("Migration file for version %s not found\n", config.BoldColor.Sprint(version))
-
-		return nil
+		return config.ConfigError("migration file for version %d not found", version)
 	}
 
 	dbConfig, ok := s.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", config.BoldColor.Sprint(schema))
-
-		return nil
+		return config.ConfigError("schema '%s' not found", schema)
 	}
 
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer db.Close()
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, migrationFolder)
+	lock, err := config.AcquireLock(db, schema, s.config.LockWaitTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	migrator, err := config.NewMigrator(db, dbConfig, schema, migrationFolder)
+	if err != nil {
+		return err
+	}
 	defer migrator.Close()
 
 	err = migrator.Force(version)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
+		return config.MigrationError(err)
+	}
 
-		return nil
+	err = config.RecordSet(db, schema, migrationFolder, uint(version))
+	if err != nil {
+		return err
 	}
 
 	config.SuccessColor.Printf("Migration on %s schema %s set to %s\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema), config.BoldColor.Sprint(version))
@@ -0,0 +1,295 @@
+package command
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+	"github.com/ad3n/kmt/v2/pkg/db"
+
+	"github.com/briandowns/spinner"
+)
+
+var (
+	// dataKeywords start statements whose effect a schema snapshot can't carry into the squashed migration.
+	dataKeywords = []string{"INSERT", "UPDATE", "DELETE", "MERGE", "COPY", "WITH", "SELECT", "CALL"}
+
+	// blockKeywords change data from inside the body of a DO block.
+	blockKeywords = []string{"INSERT", "UPDATE", "DELETE", "MERGE", "COPY", "CALL", "PERFORM", "EXECUTE"}
+)
+
+type squash struct {
+	config *config.Migration
+}
+
+func NewSquash(config *config.Migration) *squash {
+	return &squash{config: config}
+}
+
+func (s *squash) Call(source string, schema string, until uint) error {
+	if until == 0 {
+		return config.ConfigError("invalid version")
+	}
+
+	source, err := s.connection(source, schema)
+	if err != nil {
+		return err
+	}
+
+	dbConfig := s.config.Connections[source]
+	migrationFolder := filepath.Join(s.config.Folder, schema)
+	files, err := config.ListMigrations(migrationFolder)
+	if err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(files, func(file *config.MigrationFile) bool { return file.Version == until })
+	if index < 0 {
+		return config.ConfigError("migration file for version %d not found", until)
+	}
+
+	squashed := files[:index+1]
+	if len(squashed) < 2 {
+		return config.ConfigError("nothing to squash, version %d is the first migration of schema %s", until, schema)
+	}
+
+	for _, file := range squashed {
+		if err := s.check(file); err != nil {
+			return err
+		}
+	}
+
+	if err := s.versions(schema, squashed[0].Version, until); err != nil {
+		return err
+	}
+
+	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
+	progress.Suffix = fmt.Sprintf(" Applying %d migration(s) of schema %s to a scratch database on %s", len(squashed), config.SuccessColor.Sprint(schema), config.SuccessColor.Sprint(source))
+	progress.Start()
+
+	snapshot, err := replay(dbConfig, source, schema, migrationFolder, until)
+	progress.Stop()
+	if err != nil {
+		return err
+	}
+
+	migration := db.Diff(snapshot, db.EmptySnapshot(schema))
+	if migration.UpScript == "" {
+		return config.ConfigError("migrations of schema %s up to version %d create no objects", schema, until)
+	}
+
+	header := fmt.Sprintf("%s%s=%d-%d\n-- Squash of %d migration(s) of schema %s\n\n", config.HEADER_PREFIX, config.HEADER_SQUASHED, squashed[0].Version, until, len(squashed), schema)
+	name := fmt.Sprintf("%d_squashed", until)
+	paths, err := config.WriteMigration(migrationFolder, name, header+migration.UpScript, migration.DownScript, s.config.Layout)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range squashed {
+		for _, path := range slices.Compact([]string{file.Up, file.Down}) {
+			if path == "" || slices.Contains(paths, path) {
+				continue
+			}
+
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	config.SuccessColor.Printf("Squashed %d migration(s) of schema %s into %s\n", len(squashed), config.BoldColor.Sprint(schema), config.BoldColor.Sprint(name))
+
+	return nil
+}
+
+func (s *squash) connection(source string, schema string) (string, error) {
+	if source != "" {
+		dbConfig, ok := s.config.Connections[source]
+		if !ok {
+			return "", config.ConfigError("database connection '%s' not found", source)
+		}
+
+		if _, ok := dbConfig.Schemas[schema]; !ok {
+			return "", config.ConfigError("schema '%s' not found on %s", schema, source)
+		}
+
+		return source, nil
+	}
+
+	candidates := []string{}
+	for name, dbConfig := range s.config.Connections {
+		if _, ok := dbConfig.Schemas[schema]; ok {
+			candidates = append(candidates, name)
+		}
+	}
+
+	if len(candidates) != 1 {
+		return "", config.ConfigError("schema '%s' is defined on %d connection(s), choose one with --connection", schema, len(candidates))
+	}
+
+	return candidates[0], nil
+}
+
+// versions refuses to squash while a connection is at a version that the squash removes, or can't be checked.
+func (s *squash) versions(schema string, first uint, until uint) error {
+	inside, unchecked := []string{}, []string{}
+	for _, name := range slices.Sorted(maps.Keys(s.config.Connections)) {
+		dbConfig := s.config.Connections[name]
+		if _, ok := dbConfig.Schemas[schema]; !ok {
+			continue
+		}
+
+		conn, err := config.NewConnection(dbConfig)
+		if err == nil {
+			var version uint
+			version, _, err = config.CurrentVersion(conn, schema)
+			conn.Close()
+
+			if err == nil && version >= first && version < until {
+				inside = append(inside, fmt.Sprintf("%s (%d)", name, version))
+			}
+		}
+
+		if err != nil {
+			config.ErrorColor.Printf("Version of schema %s on %s could not be checked, %s\n", schema, name, err.Error())
+			unchecked = append(unchecked, name)
+		}
+	}
+
+	if len(inside) > 0 {
+		return config.OutOfSyncError("schema %s on %s is between versions %d and %d, run kmt up on them before the squash", schema, strings.Join(inside, ", "), first, until)
+	}
+
+	if len(unchecked) > 0 {
+		return config.ConnectionError(fmt.Errorf("version of schema %s on %s could not be checked, squash needs every connection of the schema to be reachable", schema, strings.Join(unchecked, ", ")))
+	}
+
+	return nil
+}
+
+// check refuses files whose result depends on the connection they run on.
+func (s *squash) check(file *config.MigrationFile) error {
+	content, err := file.Read(true)
+	if err != nil {
+		return err
+	}
+
+	header, err := config.ParseHeader(content)
+	if err != nil {
+		return fmt.Errorf("migration %s %s", filepath.Base(file.Up), err.Error())
+	}
+
+	_, only := header[config.HEADER_ONLY]
+	_, except := header[config.HEADER_EXCEPT]
+	if only || except {
+		return config.ConfigError("migration %s is limited to some connections and can't be squashed", filepath.Base(file.Up))
+	}
+
+	if _, err := config.Render(filepath.Base(file.Up), content, nil); err != nil {
+		return config.ConfigError("migration %s is a template using vars and can't be squashed", filepath.Base(file.Up))
+	}
+
+	for _, statement := range config.SplitStatements(string(content)) {
+		if keyword := dataKeyword(statement); keyword != "" {
+			return config.ConfigError("migration %s changes data with %s and can't be squashed, the squashed migration only recreates the schema", filepath.Base(file.Up), keyword)
+		}
+	}
+
+	return nil
+}
+
+// dataKeyword returns the keyword a statement changes data with, looking into the body of DO blocks such as generated enums.
+func dataKeyword(statement string) string {
+	keyword := firstKeyword(statement)
+	if slices.Contains(dataKeywords, keyword) {
+		return keyword
+	}
+
+	if keyword != "DO" {
+		return ""
+	}
+
+	for _, word := range blockWords(statement) {
+		if slices.Contains(blockKeywords, word) {
+			return "DO block with " + word
+		}
+	}
+
+	return ""
+}
+
+// blockWords returns the upper case words of a DO statement outside of its string literals and comments.
+func blockWords(statement string) []string {
+	words := []string{}
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToUpper(word.String()))
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case c == '\'':
+			flush()
+			for i++; i < len(statement); i++ {
+				if statement[i] == '\'' && (i+1 >= len(statement) || statement[i+1] != '\'') {
+					break
+				}
+
+				if statement[i] == '\'' {
+					i++
+				}
+			}
+		case c == '-' && strings.HasPrefix(statement[i:], "--"):
+			flush()
+			if end := strings.IndexByte(statement[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(statement)
+			}
+		case c == '/' && strings.HasPrefix(statement[i:], "/*"):
+			flush()
+			if end := strings.Index(statement[i:], "*/"); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(statement)
+			}
+		case c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			word.WriteByte(c)
+		default:
+			flush()
+		}
+	}
+
+	flush()
+
+	return words
+}
+
+// firstKeyword returns the upper case first word of a statement after its comments.
+func firstKeyword(statement string) string {
+	for {
+		statement = strings.TrimSpace(statement)
+		switch {
+		case strings.HasPrefix(statement, "--"):
+			_, statement, _ = strings.Cut(statement, "\n")
+		case strings.HasPrefix(statement, "/*"):
+			_, statement, _ = strings.Cut(statement, "*/")
+		default:
+			end := strings.IndexFunc(statement, func(r rune) bool { return !unicode.IsLetter(r) })
+			if end < 0 {
+				end = len(statement)
+			}
+
+			return strings.ToUpper(statement[:end])
+		}
+	}
+}
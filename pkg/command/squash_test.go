@@ -0,0 +1,56 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+	"github.com/ad3n/kmt/v2/pkg/db"
+)
+
+func TestSquashCheckAcceptsGeneratedEnum(t *testing.T) {
+	folder := t.TempDir()
+	enum := fmt.Sprintf(db.SQL_CREATE_ENUM_CLOSE, fmt.Sprintf(db.SQL_CREATE_ENUM_OPEN, "public.status")+"'insert','update'")
+	files := map[string]string{
+		"1700000001_enum_status.up.sql":    enum,
+		"1700000001_enum_status.down.sql":  fmt.Sprintf(db.SECURE_DROP_TYPE, "public.status"),
+		"1700000002_table_orders.up.sql":   "CREATE TABLE public.orders (id bigint, status public.status);\n",
+		"1700000002_table_orders.down.sql": "DROP TABLE IF EXISTS public.orders;\n",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrations, err := config.ListMigrations(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSquash(&config.Migration{})
+	for _, file := range migrations {
+		if err := s.check(file); err != nil {
+			t.Errorf("check(%s) = %v, want nil", filepath.Base(file.Up), err)
+		}
+	}
+}
+
+func TestDataKeyword(t *testing.T) {
+	cases := map[string]string{
+		"CREATE TABLE a (id int);":           "",
+		"-- seed\nINSERT INTO a VALUES (1);": "INSERT",
+		"DO $$ BEGIN CREATE TYPE s AS ENUM ('delete'); EXCEPTION WHEN duplicate_object THEN null; END $$;": "",
+		"DO $$ BEGIN INSERT INTO a VALUES (1); END $$;":                                                    "DO block with INSERT",
+		"DO $$ BEGIN /* update */ ALTER TABLE a ADD b int; END $$;":                                        "",
+	}
+
+	for statement, want := range cases {
+		if got := dataKeyword(statement); got != want {
+			t.Errorf("dataKeyword(%q) = %q, want %q", statement, got, want)
+		}
+	}
+}
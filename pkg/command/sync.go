@@ -21,9 +21,13 @@ func NewSync(config *config.Migration) *sync {
 func (s *sync) Run(source string, cluster string, schema string) error {
 	lists, ok := s.config.Clusters[cluster]
 	if !ok {
-		config.ErrorColor.Printf("Cluster '%s' isn't defined\n", config.BoldColor.Sprint(cluster))
+		return config.ConfigError("cluster '%s' isn't defined", cluster)
+	}
 
-		return nil
+	for _, c := range lists {
+		if _, ok := s.config.Connections[c]; !ok && source != c {
+			return config.ConfigError("connection '%s' isn't defined", c)
+		}
 	}
 
 	connection := make(chan *config.Connection)
@@ -35,16 +39,7 @@ func (s *sync) Run(source string, cluster string, schema string) error {
 				continue
 			}
 
-			x, ok := cConfigs[c]
-			if !ok {
-				config.ErrorColor.Printf("Connection '%s' isn't defined\n", config.BoldColor.Sprint(c))
-
-				close(connection)
-
-				break
-			}
-
-			connection <- x
+			connection <- cConfigs[c]
 			name <- c
 		}
 
@@ -55,45 +50,82 @@ func (s *sync) Run(source string, cluster string, schema string) error {
 	for source := range connection {
 		db, err := config.NewConnection(source)
 		if err != nil {
-			config.ErrorColor.Println(err.Error())
-
-			return nil
+			return err
 		}
 		defer db.Close()
 
-		migrator := config.NewMigrator(db, source.Name, schema, filepath.Join(s.config.Folder, schema))
+		lock, err := config.AcquireLock(db, schema, s.config.LockWaitTimeout)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		migrator, err := config.NewMigrator(db, source, schema, filepath.Join(s.config.Folder, schema))
+		if err != nil {
+			return err
+		}
 		defer migrator.Close()
 
 		progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
 		progress.Suffix = fmt.Sprintf(" Running migrations for %s on %s schema", config.SuccessColor.Sprint(<-name), config.BoldColor.Sprint(schema))
 		progress.Start()
 
-		err = migrator.Up()
-		if err != nil && err == gomigrate.ErrNoChange {
-			progress.Stop()
+		upErr := migrator.Up()
+		if upErr != gomigrate.ErrNoChange {
+			if _, err := cleanDirty(migrator); err != nil {
+				return err
+			}
+		}
+
+		progress.Stop()
+
+		if upErr != nil && upErr != gomigrate.ErrNoChange {
+			return config.MigrationError(upErr)
+		}
 
+		if _, err := config.ApplyRepeatables(db, source, schema, filepath.Join(s.config.Folder, schema)); err != nil {
+			return config.MigrationError(err)
+		}
+	}
+
+	config.SuccessColor.Printf("Migration synced on %s schema %s\n", config.BoldColor.Sprint(cluster), config.BoldColor.Sprint(schema))
+
+	return nil
+}
+
+func (s *sync) DryRun(source string, cluster string, schema string) error {
+	lists, ok := s.config.Clusters[cluster]
+	if !ok {
+		return config.ConfigError("cluster '%s' isn't defined", cluster)
+	}
+
+	for _, c := range lists {
+		if source == c {
 			continue
 		}
 
-		version, dirty, err := migrator.Version()
-		if err != nil {
-			return err
+		dbConfig, ok := s.config.Connections[c]
+		if !ok {
+			return config.ConfigError("connection '%s' isn't defined", c)
 		}
 
-		if version > 0 && dirty {
-			if err := migrator.Force(int(version)); err != nil {
+		err := func() error {
+			db, err := config.NewConnection(dbConfig)
+			if err != nil {
 				return err
 			}
+			defer db.Close()
 
-			if err := migrator.Steps(-1); err != nil {
+			if err := dryRun(db, c, dbConfig, schema, filepath.Join(s.config.Folder, schema), latestVersion); err != nil {
 				return err
 			}
-		}
 
-		progress.Stop()
+			return dryRunRepeatables(db, c, dbConfig, schema, filepath.Join(s.config.Folder, schema))
+		}()
+		if err != nil {
+			return err
+		}
 	}
 
-	config.SuccessColor.Printf("Migration synced on %s schema %s\n", config.BoldColor.Sprint(cluster), config.BoldColor.Sprint(schema))
-
 	return nil
 }
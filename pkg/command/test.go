@@ -27,9 +27,7 @@ func (t *test) Call() error {
 	if err := t.testFolder(); err != nil {
 		progress.Stop()
 
-		config.ErrorColor.Printf("Migration folder '%s' is not writable: %s\n", config.BoldColor.Sprint(t.config.Folder), err.Error())
-
-		return nil
+		return config.ConfigError("migration folder '%s' is not writable: %s", t.config.Folder, err.Error())
 	}
 
 	progress.Suffix = " Test connections config..."
@@ -44,9 +42,7 @@ func (t *test) Call() error {
 		if err != nil {
 			progress.Stop()
 
-			config.ErrorColor.Println(err.Error())
-
-			return nil
+			return config.ConnectionError(fmt.Errorf("connection '%s' error %w", i, err))
 		}
 		defer db.Close()
 
@@ -54,9 +50,7 @@ func (t *test) Call() error {
 		if err != nil {
 			progress.Stop()
 
-			config.ErrorColor.Printf("Connection '%s' error %s \n", config.BoldColor.Sprint(i), err.Error())
-
-			return nil
+			return config.ConnectionError(fmt.Errorf("connection '%s' error %w", i, err))
 		}
 	}
 
@@ -70,9 +64,7 @@ func (t *test) Call() error {
 	if err != nil {
 		progress.Stop()
 
-		config.ErrorColor.Printf("PG Dump not found on %s\n", config.BoldColor.Sprint(t.config.PgDump))
-
-		return nil
+		return config.ConfigError("pg_dump not found on %s", t.config.PgDump)
 	}
 
 	progress.Stop()
@@ -21,71 +21,94 @@ func NewUp(config *config.Migration) *up {
 func (u *up) Call(source string, schema string) error {
 	dbConfig, ok := u.config.Connections[source]
 	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
-
-		return nil
+		return config.ConfigError("database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", config.BoldColor.Sprint(schema))
-
-		return nil
+		return config.ConfigError("schema '%s' not found", schema)
 	}
 
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 	defer db.Close()
 
-	_, err = db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", schema))
+	lock, err := config.AcquireLock(db, schema, u.config.LockWaitTimeout)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
+		return err
+	}
+	defer lock.Release()
 
-		return nil
+	_, err = db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", schema))
+	if err != nil {
+		return err
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, filepath.Join(u.config.Folder, schema))
+	migrator, err := config.NewMigrator(db, dbConfig, schema, filepath.Join(u.config.Folder, schema))
+	if err != nil {
+		return err
+	}
 	defer migrator.Close()
 
 	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
 	progress.Suffix = fmt.Sprintf(" Running migrations for %s on %s schema", config.SuccessColor.Sprint(source), config.SuccessColor.Sprint(schema))
 	progress.Start()
 
-	err = migrator.Up()
-	if err != nil {
-		if err == gomigrate.ErrNoChange {
-			progress.Stop()
+	upErr := migrator.Up()
+	if upErr != nil && upErr != gomigrate.ErrNoChange {
+		progress.Stop()
 
-			config.SuccessColor.Printf("Database %s schema %s is up to date\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
+		return config.MigrationError(upErr)
+	}
 
-			return nil
-		}
+	if upErr == nil {
+		if _, err := cleanDirty(migrator); err != nil {
+			progress.Stop()
 
-		return err
+			return err
+		}
 	}
 
-	version, dirty, err := migrator.Version()
+	progress.Stop()
+
+	applied, err := config.ApplyRepeatables(db, dbConfig, schema, filepath.Join(u.config.Folder, schema))
 	if err != nil {
-		return err
+		return config.MigrationError(err)
 	}
 
-	if version > 0 && dirty {
-		if err := migrator.Force(int(version)); err != nil {
-			return err
-		}
+	if upErr == gomigrate.ErrNoChange && applied == 0 {
+		config.SuccessColor.Printf("Database %s schema %s is up to date\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
 
-		if err := migrator.Steps(-1); err != nil {
-			return err
-		}
+		return nil
 	}
 
-	progress.Stop()
-
 	config.SuccessColor.Printf("Migration on %s schema %s run successfully\n", config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
 
-	return err
+	return nil
+}
+
+func (u *up) DryRun(source string, schema string) error {
+	dbConfig, ok := u.config.Connections[source]
+	if !ok {
+		return config.ConfigError("database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return config.ConfigError("schema '%s' not found", schema)
+	}
+
+	db, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := dryRun(db, source, dbConfig, schema, filepath.Join(u.config.Folder, schema), latestVersion); err != nil {
+		return err
+	}
+
+	return dryRunRepeatables(db, source, dbConfig, schema, filepath.Join(u.config.Folder, schema))
 }
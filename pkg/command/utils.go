@@ -1,12 +1,32 @@
 package command
 
 import (
-	"strconv"
-	"strings"
+	"github.com/ad3n/kmt/v2/pkg/config"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
 )
 
-func parseMigrationVersion(filename string) (int, error) {
-	f := strings.Split(filename, "_")
+// cleanDirty rolls back a migration left dirty by a failure, so the schema ends on the last clean version.
+func cleanDirty(migrator *gomigrate.Migrate) (uint, error) {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return 0, err
+	}
+
+	if version > 0 && dirty {
+		if err := migrator.Force(int(version)); err != nil {
+			return 0, config.MigrationError(err)
+		}
+
+		if err := migrator.Steps(-1); err != nil {
+			return 0, config.MigrationError(err)
+		}
+
+		version, _, err = migrator.Version()
+		if err != nil && err != gomigrate.ErrNilVersion {
+			return 0, err
+		}
+	}
 
-	return strconv.Atoi(f[0])
+	return version, nil
 }
@@ -0,0 +1,130 @@
+package command
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/ad3n/kmt/v2/pkg/config"
+)
+
+var appliedStatuses = []string{config.HISTORY_APPLIED, config.HISTORY_SET, config.HISTORY_SKIPPED, config.HISTORY_BASELINE}
+
+type (
+	verify struct {
+		config *config.Migration
+	}
+
+	Drift struct {
+		History *config.History
+		File    string
+		Status  string
+	}
+)
+
+const (
+	DRIFT_CHANGED = "changed"
+	DRIFT_MISSING = "missing"
+)
+
+func NewVerify(config *config.Migration) *verify {
+	return &verify{config: config}
+}
+
+func (v *verify) Call(source string, schema string) ([]*Drift, error) {
+	dbConfig, ok := v.config.Connections[source]
+	if !ok {
+		return nil, config.ConfigError("database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return nil, config.ConfigError("schema '%s' not found", schema)
+	}
+
+	db, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	current, _, err := config.CurrentVersion(db, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	histories, err := config.LatestHistories(db, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := config.ListMigrations(filepath.Join(v.config.Folder, schema))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[uint]*config.MigrationFile, len(files))
+	squashed := [][2]uint{}
+	for _, file := range files {
+		versions[file.Version] = file
+
+		if content, err := file.Read(true); err == nil {
+			if header, err := config.ParseHeader(content); err == nil {
+				if from, until, ok := header.Squashed(); ok {
+					squashed = append(squashed, [2]uint{from, until})
+				}
+			}
+		}
+	}
+
+	drifts := []*Drift{}
+	checked := 0
+	for _, history := range histories {
+		if history.Version > current || !slices.Contains(appliedStatuses, history.Status) {
+			continue
+		}
+
+		file, ok := versions[history.Version]
+		if isSquashed(squashed, history.Version) {
+			if !ok {
+				continue
+			}
+
+			if checksum, err := file.Checksum(true); err != nil || checksum != history.Checksum {
+				continue
+			}
+		}
+
+		checked++
+		if !ok || file.Up == "" {
+			drifts = append(drifts, &Drift{History: history, File: history.Name, Status: DRIFT_MISSING})
+
+			continue
+		}
+
+		checksum, err := file.Checksum(true)
+		if err != nil {
+			return nil, err
+		}
+
+		if checksum != history.Checksum {
+			drifts = append(drifts, &Drift{History: history, File: filepath.Base(file.Up), Status: DRIFT_CHANGED})
+		}
+	}
+
+	if len(drifts) == 0 {
+		config.SuccessColor.Printf("All %s applied migration(s) on %s schema %s match their files\n", config.BoldColor.Sprint(checked), config.BoldColor.Sprint(source), config.BoldColor.Sprint(schema))
+	}
+
+	return drifts, nil
+}
+
+// isSquashed reports whether a version was replaced by a squashed migration, its history predates the squash.
+func isSquashed(ranges [][2]uint, version uint) bool {
+	for _, squashed := range ranges {
+		if version >= squashed[0] && version <= squashed[1] {
+			return true
+		}
+	}
+
+	return false
+}
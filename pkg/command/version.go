@@ -1,95 +1,139 @@
 package command
 
 import (
-	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/ad3n/kmt/v2/pkg/config"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
 )
 
-type version struct {
-	config *config.Migration
-}
+type (
+	version struct {
+		config *config.Migration
+	}
+
+	VersionRecord struct {
+		Connection string `json:"connection" yaml:"connection"`
+		Schema     string `json:"schema" yaml:"schema"`
+		File       uint   `json:"file_version" yaml:"file_version"`
+		Database   uint   `json:"db_version" yaml:"db_version"`
+		Sync       bool   `json:"sync" yaml:"sync"`
+		Diff       int    `json:"diff" yaml:"diff"`
+	}
+)
 
 func NewVersion(config *config.Migration) *version {
 	return &version{config: config}
 }
 
-func (v *version) Call(source string, schema string) (uint, uint, int) {
-	dbConfig, ok := v.config.Connections[source]
-	if !ok {
-		config.ErrorColor.Printf("Database connection '%s' not found\n", config.BoldColor.Sprint(source))
+func (v *version) Records(name string, schema string) ([]*VersionRecord, error) {
+	if schema != "" {
+		record, err := v.Call(name, schema)
+		if err != nil {
+			return nil, err
+		}
 
-		return 0, 0, 0
+		return []*VersionRecord{record}, nil
 	}
 
-	_, ok = dbConfig.Schemas[schema]
+	connections, ok := v.config.Clusters[name]
 	if !ok {
-		config.ErrorColor.Printf("Schema '%s' not found\n", config.BoldColor.Sprint(schema))
+		if _, ok := v.config.Connections[name]; !ok {
+			return nil, config.ConfigError("cluster/connection '%s' not found", name)
+		}
 
-		return 0, 0, 0
+		connections = []string{name}
 	}
 
-	db, err := config.NewConnection(dbConfig)
-	if err != nil {
-		config.ErrorColor.Println(err.Error())
+	records := []*VersionRecord{}
+	for _, connection := range connections {
+		source, ok := v.config.Connections[connection]
+		if !ok {
+			return nil, config.ConfigError("connection for '%s' not found", connection)
+		}
+
+		schemas := make([]string, 0, len(source.Schemas))
+		for k := range source.Schemas {
+			schemas = append(schemas, k)
+		}
+
+		sort.Strings(schemas)
+
+		for _, k := range schemas {
+			record, err := v.Call(connection, k)
+			if err != nil {
+				return nil, err
+			}
 
-		return 0, 0, 0
+			records = append(records, record)
+		}
 	}
-	defer db.Close()
 
-	migrationFolder := filepath.Join(v.config.Folder, schema)
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, migrationFolder)
-	defer migrator.Close()
+	return records, nil
+}
 
-	version, _, err := migrator.Version()
-	if err != nil {
-		config.ErrorColor.Println(err.Error())
+func (v *version) Call(source string, schema string) (*VersionRecord, error) {
+	dbConfig, ok := v.config.Connections[source]
+	if !ok {
+		return nil, config.ConfigError("database connection '%s' not found", source)
+	}
 
-		return 0, 0, 0
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return nil, config.ConfigError("schema '%s' not found on %s", schema, source)
 	}
 
-	files, err := os.ReadDir(migrationFolder)
+	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
+		return nil, err
+	}
+	defer db.Close()
 
-		return 0, 0, 0
+	migrationFolder := filepath.Join(v.config.Folder, schema)
+	migrator, err := config.NewMigrator(db, dbConfig, schema, migrationFolder)
+	if err != nil {
+		return nil, err
 	}
+	defer migrator.Close()
+
+	record := VersionRecord{Connection: source, Schema: schema}
 
-	filesLength := len(files)
-	if filesLength == 0 {
-		return 0, 0, 0
+	version, _, err := migrator.Version()
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return nil, err
 	}
 
-	vFile, err := parseMigrationVersion(files[filesLength-1].Name())
+	files, err := config.ListMigrations(migrationFolder)
 	if err != nil {
-		config.ErrorColor.Println(err.Error())
-
-		return 0, 0, 0
+		return nil, err
 	}
 
-	valid := false
-	number := 0
-	for i, file := range files {
-		if i%2 == 0 {
-			continue
-		}
+	if len(files) == 0 {
+		record.Database = version
+		record.Sync = version == 0
 
-		s, _ := parseMigrationVersion(file.Name())
-		if !valid && (version == uint(s) || vFile == s) {
-			valid = true
+		return &record, nil
+	}
 
-			continue
-		}
+	vFile := files[len(files)-1].Version
 
-		if valid {
+	number := 0
+	for _, file := range files {
+		if file.Version > version {
 			number++
 		}
 	}
 
-	if version < uint(vFile) {
+	if version < vFile {
 		number = number * -1
 	}
 
-	return version, uint(vFile), number
+	record.File = vFile
+	record.Database = version
+	record.Sync = record.File == record.Database
+	record.Diff = number
+
+	return &record, nil
 }
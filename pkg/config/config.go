@@ -3,86 +3,215 @@ package config
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 
 	"github.com/goccy/go-yaml"
+	"github.com/jackc/pgpassfile"
 
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 type (
 	Config struct {
-		Migration *Migration `yaml:"migration"`
+		Migration *Migration `yaml:"migration" json:"migration"`
+
+		source []byte
 	}
 
 	Migration struct {
-		Clusters    map[string][]string    `yaml:"clusters"`
-		Connections map[string]*Connection `yaml:"connections"`
-		PgDump      string                 `yaml:"pg_dump"`
-		Folder      string                 `yaml:"folder"`
+		Clusters         map[string][]string    `yaml:"clusters" json:"clusters"`
+		Connections      map[string]*Connection `yaml:"connections" json:"connections"`
+		PgDump           string                 `yaml:"pg_dump" json:"pg_dump"`
+		Folder           string                 `yaml:"folder" json:"folder"`
+		Layout           string                 `yaml:"layout" json:"layout"`
+		LockWait         string                 `yaml:"lock_wait" json:"lock_wait"`
+		LockTimeout      string                 `yaml:"lock_timeout,omitempty" json:"lock_timeout,omitempty"`
+		StatementTimeout string                 `yaml:"statement_timeout,omitempty" json:"statement_timeout,omitempty"`
+		Retry            *Retry                 `yaml:"retry" json:"retry"`
+		LockWaitTimeout  time.Duration          `yaml:"-" json:"-"`
 	}
 
 	Connection struct {
-		Schemas  map[string]map[string][]string `yaml:"schemas"`
-		Options  map[string]string              `yaml:"options"`
-		Host     string                         `yaml:"host"`
-		Name     string                         `yaml:"name"`
-		User     string                         `yaml:"user"`
-		Password string                         `yaml:"password"`
-		Port     int                            `yaml:"port"`
+		Schemas          map[string]*Schema `yaml:"schemas" json:"schemas"`
+		Options          map[string]string  `yaml:"options" json:"options"`
+		Host             string             `yaml:"host" json:"host"`
+		Name             string             `yaml:"name" json:"name"`
+		User             string             `yaml:"user" json:"user"`
+		Password         string             `yaml:"password" json:"password"`
+		PasswordFile     string             `yaml:"password_file,omitempty" json:"password_file,omitempty"`
+		PasswordEnv      string             `yaml:"password_env,omitempty" json:"password_env,omitempty"`
+		Port             int                `yaml:"port" json:"port"`
+		URL              string             `yaml:"url,omitempty" json:"url,omitempty"`
+		Service          string             `yaml:"service,omitempty" json:"service,omitempty"`
+		LockTimeout      string             `yaml:"lock_timeout,omitempty" json:"lock_timeout,omitempty"`
+		StatementTimeout string             `yaml:"statement_timeout,omitempty" json:"statement_timeout,omitempty"`
+		Retry            *Retry             `yaml:"retry,omitempty" json:"retry,omitempty"`
+		Vars             map[string]string  `yaml:"vars,omitempty" json:"vars,omitempty"`
+		Alias            string             `yaml:"-" json:"-"`
+		Clusters         []string           `yaml:"-" json:"-"`
+	}
+
+	Schema struct {
+		Excludes         []string          `yaml:"excludes" json:"excludes"`
+		WithData         []string          `yaml:"with_data" json:"with_data"`
+		LockTimeout      string            `yaml:"lock_timeout,omitempty" json:"lock_timeout,omitempty"`
+		StatementTimeout string            `yaml:"statement_timeout,omitempty" json:"statement_timeout,omitempty"`
+		Retry            *Retry            `yaml:"retry,omitempty" json:"retry,omitempty"`
+		Vars             map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
+	}
+
+	Retry struct {
+		Attempts int    `yaml:"attempts" json:"attempts"`
+		Backoff  string `yaml:"backoff" json:"backoff"`
 	}
 )
 
 func NewConnection(database *Connection) (*sql.DB, error) {
-	options := strings.Builder{}
-	for k, v := range database.Options {
-		options.WriteString(k)
-		options.WriteString("=")
-		options.WriteString(v)
-		options.WriteString(" ")
+	dsn, err := database.DSN()
+	if err != nil {
+		return nil, ConfigError("%s", err.Error())
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, ConnectionError(err)
 	}
 
-	return sql.Open("pgx", fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s %s", database.Host, database.Port, database.User, database.Password, database.Name, strings.TrimRight(options.String(), " ")))
+	if err := db.Ping(); err != nil {
+		db.Close()
+
+		return nil, ConnectionError(err)
+	}
+
+	return db, nil
 }
 
-func NewMigrator(db *sql.DB, database, schema string, path string) *migrate.Migrate {
+func NewMigrator(db *sql.DB, connection *Connection, schema string, path string) (*migrate.Migrate, error) {
 	driver, err := postgres.WithInstance(db, &postgres.Config{SchemaName: schema})
 	if err != nil {
-		log.Fatalln(err.Error())
+		return nil, ConnectionError(err)
 	}
 
-	wd, err := os.Getwd()
-	if err != nil {
-		log.Fatalln(err.Error())
+	if !filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		path = filepath.Join(wd, path)
 	}
 
-	migrate, err := migrate.NewWithDatabaseInstance(fmt.Sprintf("file://%s", filepath.Join(wd, path)), database, driver)
+	limits, retry := schemaLimits(connection, schema)
+
+	migrate, err := migrate.NewWithDatabaseInstance(fmt.Sprintf("kmt://%s", path), connection.Name, newHistoryDriver(driver, db, connection, schema, path, limits, retry))
 	if err != nil {
-		log.Fatalln(err.Error())
+		return nil, NewExitError(EXIT_CONFIG, err)
 	}
 
-	return migrate
+	return migrate, nil
 }
 
-func Parse(path string) *Config {
-	config := Config{}
-	c, err := os.ReadFile(path)
+func schemaLimits(connection *Connection, schema string) (timeouts, *Retry) {
+	settings, ok := connection.Schemas[schema]
+	if !ok || settings == nil {
+		return timeouts{}, &Retry{Attempts: 1}
+	}
+
+	retry := settings.Retry
+	if retry == nil {
+		retry = &Retry{Attempts: 1}
+	}
+
+	return timeouts{lock: settings.LockTimeout, statement: settings.StatementTimeout}, retry
+}
+
+func Load(path string, env string) (*Config, error) {
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		path, err = Find(wd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return Parse(path, env)
+}
+
+func Find(dir string) (string, error) {
+	for {
+		path := filepath.Join(dir, CONFIG_FILE)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ConfigError("%s not found in current or any parent directory", CONFIG_FILE)
+		}
+
+		dir = parent
+	}
+}
+
+func Parse(path string, env string) (*Config, error) {
+	path, err := filepath.Abs(path)
 	if err != nil {
-		log.Fatalf("Kmtfile.yml not found")
+		return nil, NewExitError(EXIT_CONFIG, err)
 	}
 
+	l := &loader{}
+	values, err := l.read(path, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	if env != "" {
+		overlay, err := l.read(OverlayPath(path, env), map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+
+		values = merge(values, overlay)
+	}
+
+	c, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, l.errOr(ConfigError("%s", err.Error()))
+	}
+
+	config := Config{}
 	err = yaml.Unmarshal(c, &config)
 	if err != nil {
-		log.Fatalln(err.Error())
+		return nil, l.errOr(ConfigError("%s", err.Error()))
+	}
+
+	if config.Migration == nil {
+		return nil, l.errOr(ConfigError("%s has no migration section", path))
+	}
+
+	err = interpolate(reflect.ValueOf(&config))
+	if err != nil {
+		l.add(path, nil, "%s", err.Error())
+
+		return nil, l.err()
 	}
 
+	base := filepath.Dir(path)
+
 	if config.Migration.PgDump == "" {
 		config.Migration.PgDump = "pg_dump"
 	}
@@ -91,27 +220,139 @@ func Parse(path string) *Config {
 		config.Migration.Folder = "migrations"
 	}
 
+	if !filepath.IsAbs(config.Migration.Folder) {
+		config.Migration.Folder = filepath.Join(base, config.Migration.Folder)
+	}
+
+	if config.Migration.Layout == "" {
+		config.Migration.Layout = LAYOUT_SPLIT
+	}
+
+	if config.Migration.LockWait == "" {
+		config.Migration.LockWait = DEFAULT_LOCK_WAIT
+	}
+
+	config.source, _ = yaml.Marshal(&config)
+
+	l.validate(path, &config)
+	if err := l.err(); err != nil {
+		return nil, err
+	}
+
+	config.Migration.Retry = config.Migration.Retry.inherit(&Retry{Attempts: DEFAULT_RETRY_ATTEMPTS, Backoff: DEFAULT_RETRY_BACKOFF})
+
+	config.Migration.LockWaitTimeout, _ = time.ParseDuration(config.Migration.LockWait)
+
 	for k, cs := range config.Migration.Connections {
+		cs.Alias = k
+		for _, name := range slices.Sorted(maps.Keys(config.Migration.Clusters)) {
+			if slices.Contains(config.Migration.Clusters[name], k) {
+				cs.Clusters = append(cs.Clusters, name)
+			}
+		}
+
+		if cs.LockTimeout == "" {
+			cs.LockTimeout = config.Migration.LockTimeout
+		}
+
+		if cs.StatementTimeout == "" {
+			cs.StatementTimeout = config.Migration.StatementTimeout
+		}
+
+		cs.Retry = cs.Retry.inherit(config.Migration.Retry)
+
 		for x, v := range cs.Schemas {
 			if v == nil {
-				v = map[string][]string{}
+				v = &Schema{}
+				cs.Schemas[x] = v
 			}
 
-			_, ok := v["excludes"]
-			if !ok {
-				v["excludes"] = []string{}
+			if v.LockTimeout == "" {
+				v.LockTimeout = cs.LockTimeout
 			}
 
-			v["excludes"] = append(v["excludes"], "schema_migrations")
+			if v.StatementTimeout == "" {
+				v.StatementTimeout = cs.StatementTimeout
+			}
 
-			_, ok = v["with_data"]
-			if !ok {
-				v["with_data"] = []string{}
+			v.Retry = v.Retry.inherit(cs.Retry)
+
+			for name, value := range cs.Vars {
+				if _, ok := v.Vars[name]; !ok {
+					if v.Vars == nil {
+						v.Vars = map[string]string{}
+					}
+
+					v.Vars[name] = value
+				}
+			}
+
+			v.Excludes = append(v.Excludes, TrackingTables...)
+
+			if v.WithData == nil {
+				v.WithData = []string{}
 			}
+		}
 
-			config.Migration.Connections[k].Schemas[x] = v
+		if cs.PasswordFile != "" && !filepath.IsAbs(cs.PasswordFile) {
+			cs.PasswordFile = filepath.Join(base, cs.PasswordFile)
 		}
+
+		if err := cs.resolvePassword(); err != nil {
+			return nil, ConfigError("connection '%s' %s", k, err.Error())
+		}
+	}
+
+	return &config, nil
+}
+
+func (c *Connection) resolvePassword() error {
+	if c.Password != "" {
+		return nil
+	}
+
+	if c.PasswordEnv != "" {
+		password, ok := os.LookupEnv(c.PasswordEnv)
+		if !ok {
+			return fmt.Errorf("password_env %s is not set", c.PasswordEnv)
+		}
+
+		c.Password = password
+
+		return nil
+	}
+
+	if c.PasswordFile != "" {
+		password, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("password_file %s", err.Error())
+		}
+
+		c.Password = strings.TrimRight(string(password), "\r\n")
+
+		return nil
 	}
 
-	return &config
+	if c.Host == "" {
+		return nil
+	}
+
+	path := os.Getenv("PGPASSFILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+
+		path = filepath.Join(home, ".pgpass")
+	}
+
+	passfile, err := pgpassfile.ReadPassfile(path)
+	if err != nil {
+		return nil
+	}
+
+	c.Password = passfile.FindPassword(c.Host, strconv.Itoa(c.Port), c.Name, c.User)
+
+	return nil
 }
@@ -15,6 +15,8 @@ const (
 	REPOSITORY = "https://github.com/ad3n/kmt.git"
 
 	CONFIG_FILE = "Kmtfile.yml"
+
+	APPLICATION_NAME = "kmt"
 )
 
 var (
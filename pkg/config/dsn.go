@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var dsnReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func (c *Connection) DSN() (string, error) {
+	if c.URL != "" {
+		return c.urlDSN()
+	}
+
+	pairs := [][2]string{
+		{"host", c.Host},
+		{"port", ""},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.Name},
+		{"service", c.Service},
+	}
+
+	if c.Port != 0 {
+		pairs[1][1] = strconv.Itoa(c.Port)
+	}
+
+	keys := make([]string, 0, len(c.Options))
+	for k := range c.Options {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		pairs = append(pairs, [2]string{k, c.Options[k]})
+	}
+
+	if _, ok := c.Options["application_name"]; !ok {
+		pairs = append(pairs, [2]string{"application_name", APPLICATION_NAME})
+	}
+
+	dsn := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair[1] == "" {
+			continue
+		}
+
+		dsn = append(dsn, fmt.Sprintf("%s='%s'", pair[0], dsnReplacer.Replace(pair[1])))
+	}
+
+	return strings.Join(dsn, " "), nil
+}
+
+// WithDatabase returns a copy of the connection pointing to another database on the same server.
+func (c *Connection) WithDatabase(name string) *Connection {
+	clone := *c
+	clone.Name = name
+	if u, err := url.Parse(c.URL); c.URL != "" && err == nil {
+		u.Path = "/" + name
+		clone.URL = u.String()
+	}
+
+	return &clone
+}
+
+func (c *Connection) urlDSN() (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %s", err.Error())
+	}
+
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return "", fmt.Errorf("invalid url: scheme must be postgres or postgresql")
+	}
+
+	if c.Password != "" && u.User != nil {
+		if _, ok := u.User.Password(); !ok {
+			u.User = url.UserPassword(u.User.Username(), c.Password)
+		}
+	}
+
+	query := u.Query()
+	for k, v := range c.Options {
+		if !query.Has(k) {
+			query.Set(k, v)
+		}
+	}
+
+	if !query.Has("application_name") {
+		query.Set("application_name", APPLICATION_NAME)
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
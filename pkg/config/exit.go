@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+const (
+	EXIT_FAILURE     = 1
+	EXIT_CONFIG      = 2
+	EXIT_CONNECTION  = 3
+	EXIT_MIGRATION   = 4
+	EXIT_DIRTY       = 5
+	EXIT_OUT_OF_SYNC = 6
+	EXIT_DRIFT       = 7
+	EXIT_LOCKED      = 8
+)
+
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+func NewExitError(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return err
+	}
+
+	return &ExitError{Code: code, Err: err}
+}
+
+func ConfigError(format string, a ...any) error {
+	return NewExitError(EXIT_CONFIG, fmt.Errorf(format, a...))
+}
+
+func ConnectionError(err error) error {
+	return NewExitError(EXIT_CONNECTION, err)
+}
+
+func MigrationError(err error) error {
+	var dirty migrate.ErrDirty
+	if errors.As(err, &dirty) {
+		return NewExitError(EXIT_DIRTY, err)
+	}
+
+	return NewExitError(EXIT_MIGRATION, err)
+}
+
+func DirtyError(format string, a ...any) error {
+	return NewExitError(EXIT_DIRTY, fmt.Errorf(format, a...))
+}
+
+func OutOfSyncError(format string, a ...any) error {
+	return NewExitError(EXIT_OUT_OF_SYNC, fmt.Errorf(format, a...))
+}
+
+func DriftError(format string, a ...any) error {
+	return NewExitError(EXIT_DRIFT, fmt.Errorf(format, a...))
+}
+
+func LockError(format string, a ...any) error {
+	return NewExitError(EXIT_LOCKED, fmt.Errorf(format, a...))
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	HEADER_PREFIX = "-- kmt:"
+
+	HEADER_LOCK_TIMEOUT      = "lock_timeout"
+	HEADER_STATEMENT_TIMEOUT = "statement_timeout"
+	HEADER_NO_TRANSACTION    = "no-transaction"
+	HEADER_ONLY              = "only"
+	HEADER_EXCEPT            = "except"
+	HEADER_SQUASHED          = "squashed"
+	HEADER_TEMPLATE          = "template"
+)
+
+var headerKeys = []string{HEADER_LOCK_TIMEOUT, HEADER_STATEMENT_TIMEOUT, HEADER_NO_TRANSACTION, HEADER_ONLY, HEADER_EXCEPT, HEADER_SQUASHED, HEADER_TEMPLATE}
+
+type Header map[string]string
+
+// ParseHeader reads the `-- kmt:key=value` comments at the top of a migration file, up to the first statement.
+func ParseHeader(content []byte) (Header, error) {
+	header := Header{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		directive, ok := strings.CutPrefix(line, HEADER_PREFIX)
+		if !ok {
+			continue
+		}
+
+		key, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		key = strings.TrimSpace(key)
+		if !slices.Contains(headerKeys, key) {
+			return nil, fmt.Errorf("unknown header '%s%s', expected one of %s", HEADER_PREFIX, key, strings.Join(headerKeys, ", "))
+		}
+
+		value = strings.TrimSpace(value)
+		if (key == HEADER_ONLY || key == HEADER_EXCEPT) && len(headerList(value)) == 0 {
+			return nil, fmt.Errorf("header '%s%s' needs a comma separated list of connections or clusters", HEADER_PREFIX, key)
+		}
+
+		header[key] = value
+		if _, _, ok := header.Squashed(); key == HEADER_SQUASHED && !ok {
+			return nil, fmt.Errorf("header '%s%s' needs a version range, e.g. %s%s=1700000000-1700009999", HEADER_PREFIX, key, HEADER_PREFIX, key)
+		}
+	}
+
+	return header, scanner.Err()
+}
+
+// runs reports whether a script with this header runs on the connection, matched by its name or one of its clusters.
+func (h Header) runs(connection *Connection) bool {
+	if only, ok := h[HEADER_ONLY]; ok && !connection.matches(headerList(only)) {
+		return false
+	}
+
+	if except, ok := h[HEADER_EXCEPT]; ok && connection.matches(headerList(except)) {
+		return false
+	}
+
+	return true
+}
+
+func (h Header) scope() string {
+	scope := []string{}
+	for _, key := range []string{HEADER_ONLY, HEADER_EXCEPT} {
+		if value, ok := h[key]; ok {
+			scope = append(scope, fmt.Sprintf("%s%s=%s", HEADER_PREFIX, key, value))
+		}
+	}
+
+	return strings.Join(scope, " ")
+}
+
+// Scope returns the header deciding where a script runs. A down script without only or except follows its up script.
+func (f *MigrationFile) Scope(up bool, header Header) Header {
+	_, only := header[HEADER_ONLY]
+	_, except := header[HEADER_EXCEPT]
+	if up || only || except || f.Path(true) == "" {
+		return header
+	}
+
+	content, err := f.Read(true)
+	if err != nil {
+		return header
+	}
+
+	upHeader, err := ParseHeader(content)
+	if err != nil {
+		return header
+	}
+
+	return upHeader
+}
+
+// Skipped describes why a script does not run on the connection, or returns an empty string when it runs.
+func (h Header) Skipped(connection *Connection) string {
+	if h.runs(connection) {
+		return ""
+	}
+
+	return fmt.Sprintf("skipped on connection %s by %s", connection.Alias, h.scope())
+}
+
+// Squashed returns the first and last version replaced by a squashed migration.
+func (h Header) Squashed() (uint, uint, bool) {
+	value, ok := h[HEADER_SQUASHED]
+	if !ok {
+		return 0, 0, false
+	}
+
+	first, last, ok := strings.Cut(value, "-")
+	from, err := strconv.ParseUint(strings.TrimSpace(first), 10, 0)
+	if !ok || err != nil {
+		return 0, 0, false
+	}
+
+	until, err := strconv.ParseUint(strings.TrimSpace(last), 10, 0)
+	if err != nil || from > until {
+		return 0, 0, false
+	}
+
+	return uint(from), uint(until), true
+}
+
+func headerList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func (c *Connection) matches(names []string) bool {
+	for _, name := range names {
+		if name == c.Alias || slices.Contains(c.Clusters, name) {
+			return true
+		}
+	}
+
+	return false
+}
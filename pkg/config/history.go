@@ -0,0 +1,356 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	HISTORY_TABLE = "kmt_migrations"
+
+	HISTORY_APPLIED     = "applied"
+	HISTORY_ROLLED_BACK = "rolled_back"
+	HISTORY_FAILED      = "failed"
+	HISTORY_SET         = "set"
+	HISTORY_SKIPPED     = "skipped"
+	HISTORY_BASELINE    = "baseline"
+
+	SQL_CREATE_HISTORY = `CREATE TABLE IF NOT EXISTS %s.%s (
+    id bigserial PRIMARY KEY,
+    version bigint NOT NULL,
+    name text NOT NULL,
+    checksum text NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now(),
+    duration_ms bigint NOT NULL DEFAULT 0,
+    os_user text NOT NULL DEFAULT '',
+    kmt_version text NOT NULL DEFAULT '',
+    status text NOT NULL DEFAULT 'applied',
+    attempts integer NOT NULL DEFAULT 1,
+    retry_wait_ms bigint NOT NULL DEFAULT 0
+)`
+	SQL_INSERT_HISTORY   = "INSERT INTO %s.%s (version, name, checksum, duration_ms, os_user, kmt_version, status, attempts, retry_wait_ms) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+	QUERY_HISTORY        = "SELECT version, name, checksum, applied_at, duration_ms, os_user, kmt_version, status, attempts, retry_wait_ms FROM %s.%s ORDER BY id"
+	QUERY_HISTORY_LATEST = "SELECT DISTINCT ON (version) version, name, checksum, applied_at, duration_ms, os_user, kmt_version, status, attempts, retry_wait_ms FROM %s.%s ORDER BY version, id DESC"
+)
+
+type (
+	History struct {
+		Version    uint
+		Name       string
+		Checksum   string
+		AppliedAt  time.Time
+		Duration   time.Duration
+		OsUser     string
+		KmtVersion string
+		Status     string
+		Attempts   int
+		RetryWait  time.Duration
+	}
+
+	historyDriver struct {
+		database.Driver
+		db         *sql.DB
+		connection *Connection
+		schema     string
+		folder     string
+		files      map[uint]*MigrationFile
+		timeouts   timeouts
+		retry      *Retry
+		attempts   int
+		waited     time.Duration
+		previous   int
+		target     int
+		started    time.Time
+		running    bool
+		skipped    bool
+	}
+)
+
+func newHistoryDriver(driver database.Driver, db *sql.DB, connection *Connection, schema string, folder string, timeouts timeouts, retry *Retry) *historyDriver {
+	return &historyDriver{Driver: driver, db: db, connection: connection, schema: schema, folder: folder, timeouts: timeouts, retry: retry}
+}
+
+func (h *historyDriver) Run(migration io.Reader) error {
+	err := h.run(migration)
+	if err != nil && h.running {
+		h.running = false
+		if version, up := h.direction(); version > 0 {
+			if err := recordHistory(h.db, h.schema, h.files, version, up, HISTORY_FAILED, time.Since(h.started), h.attempts, h.waited); err != nil {
+				ErrorColor.Printf("Failed migration %d could not be recorded in %s, %s\n", version, HISTORY_TABLE, err.Error())
+			}
+		}
+	}
+
+	return err
+}
+
+func (h *historyDriver) run(migration io.Reader) error {
+	content, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	name := h.fileName()
+
+	content, err = Render(name, content, h.connection.SchemaVars(h.schema))
+	if err != nil {
+		return err
+	}
+
+	header, err := ParseHeader(content)
+	if err != nil {
+		return fmt.Errorf("migration %s %s", name, err.Error())
+	}
+
+	version, up := h.direction()
+	if file, ok := h.files[version]; ok {
+		if reason := file.Scope(up, header).Skipped(h.connection); reason != "" {
+			h.skipped = true
+			BoldColor.Printf("\nMigration %s %s, recorded without running\n", name, reason)
+
+			return nil
+		}
+	}
+
+	limits := h.timeouts.override(header)
+
+	session, err := limits.sql()
+	if err != nil {
+		return fmt.Errorf("migration %s %s", name, err.Error())
+	}
+
+	if err := h.Driver.Run(strings.NewReader(session)); err != nil {
+		return err
+	}
+
+	if _, ok := header[HEADER_NO_TRANSACTION]; !ok {
+		return limits.explain(name, h.exec(name, limits, content))
+	}
+
+	statements := SplitStatements(string(content))
+	for i, statement := range statements {
+		if err := h.exec(name, limits, []byte(statement)); err != nil {
+			return fmt.Errorf("migration %s runs without transaction and failed at statement %d of %d, the %d statement(s) before it stay applied: %w", name, i+1, len(statements), i, limits.explain(name, err))
+		}
+	}
+
+	return nil
+}
+
+// exec runs the migration body and retries it while it fails with lock_not_available.
+func (h *historyDriver) exec(name string, limits timeouts, body []byte) error {
+	for attempt := 1; ; attempt++ {
+		err := h.Driver.Run(bytes.NewReader(body))
+		if err == nil || PgCode(err) != PG_LOCK_NOT_AVAILABLE || attempt >= h.retry.Attempts {
+			return err
+		}
+
+		wait := h.retry.Wait(attempt)
+		ErrorColor.Printf("\nMigration %s hit lock_timeout %s, attempt %d of %d, retrying in %s\n", name, limits.lock, attempt, h.retry.Attempts, wait)
+
+		time.Sleep(wait)
+		h.attempts++
+		h.waited += wait
+	}
+}
+
+func (h *historyDriver) SetVersion(version int, dirty bool) error {
+	if dirty {
+		previous, _, err := h.Driver.Version()
+		if err != nil {
+			return err
+		}
+
+		if h.files == nil {
+			files, err := ListMigrations(h.folder)
+			if err != nil {
+				return err
+			}
+
+			h.files = migrationVersions(files)
+		}
+
+		h.previous = previous
+		h.target = version
+		h.started = time.Now()
+		h.running = true
+		h.attempts = 1
+		h.waited = 0
+		h.skipped = false
+
+		return h.Driver.SetVersion(version, dirty)
+	}
+
+	if err := h.Driver.SetVersion(version, dirty); err != nil {
+		return err
+	}
+
+	if !h.running || h.target != version {
+		return nil
+	}
+
+	h.running = false
+
+	file, up := h.direction()
+	if file == 0 {
+		return nil
+	}
+
+	status := HISTORY_APPLIED
+	switch {
+	case h.skipped:
+		status = HISTORY_SKIPPED
+	case !up:
+		status = HISTORY_ROLLED_BACK
+	}
+
+	if h.attempts > 1 {
+		SuccessColor.Printf("\nMigration %s succeeded after %d attempts, waited %s\n", h.fileName(), h.attempts, h.waited)
+	}
+
+	return recordHistory(h.db, h.schema, h.files, file, up, status, time.Since(h.started), h.attempts, h.waited)
+}
+
+func (h *historyDriver) direction() (uint, bool) {
+	if h.target > h.previous {
+		return uint(h.target), true
+	}
+
+	if h.previous < 0 {
+		return 0, false
+	}
+
+	return uint(h.previous), false
+}
+
+func (h *historyDriver) fileName() string {
+	version, up := h.direction()
+
+	file, ok := h.files[version]
+	if !ok {
+		return strconv.Itoa(h.target)
+	}
+
+	return filepath.Base(file.Path(up))
+}
+
+func RecordSet(db *sql.DB, schema string, folder string, version uint) error {
+	files, err := ListMigrations(folder)
+	if err != nil {
+		return err
+	}
+
+	return recordHistory(db, schema, migrationVersions(files), version, true, HISTORY_SET, 0, 1, 0)
+}
+
+func RecordBaseline(db *sql.DB, schema string, folder string, version uint) error {
+	files, err := ListMigrations(folder)
+	if err != nil {
+		return err
+	}
+
+	return recordHistory(db, schema, migrationVersions(files), version, true, HISTORY_BASELINE, 0, 1, 0)
+}
+
+func recordHistory(db *sql.DB, schema string, files map[uint]*MigrationFile, version uint, up bool, status string, duration time.Duration, attempts int, waited time.Duration) error {
+	if err := ensureHistory(db, schema); err != nil {
+		return err
+	}
+
+	name, checksum := "", ""
+	if file, ok := files[version]; ok {
+		if path := file.Path(up); path != "" {
+			name = filepath.Base(path)
+
+			var err error
+			checksum, err = file.Checksum(up)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := db.Exec(fmt.Sprintf(SQL_INSERT_HISTORY, schema, HISTORY_TABLE), int64(version), name, checksum, duration.Milliseconds(), osUser(), VERSION_STRING, status, max(attempts, 1), waited.Milliseconds())
+
+	return err
+}
+
+func ensureHistory(db *sql.DB, schema string) error {
+	_, err := db.Exec(fmt.Sprintf(SQL_CREATE_HISTORY, schema, HISTORY_TABLE))
+
+	return err
+}
+
+func migrationVersions(files []*MigrationFile) map[uint]*MigrationFile {
+	versions := make(map[uint]*MigrationFile, len(files))
+	for _, file := range files {
+		versions[file.Version] = file
+	}
+
+	return versions
+}
+
+func Histories(db *sql.DB, schema string) ([]*History, error) {
+	return histories(db, schema, QUERY_HISTORY)
+}
+
+func LatestHistories(db *sql.DB, schema string) ([]*History, error) {
+	return histories(db, schema, QUERY_HISTORY_LATEST)
+}
+
+func histories(db *sql.DB, schema string, query string) ([]*History, error) {
+	rows, err := db.Query(fmt.Sprintf(query, schema, HISTORY_TABLE))
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "42P01" || pgErr.Code == "3F000") {
+		return []*History{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []*History{}
+	for rows.Next() {
+		var (
+			version   int64
+			duration  int64
+			retryWait int64
+		)
+
+		history := History{}
+		if err := rows.Scan(&version, &history.Name, &history.Checksum, &history.AppliedAt, &duration, &history.OsUser, &history.KmtVersion, &history.Status, &history.Attempts, &retryWait); err != nil {
+			return nil, err
+		}
+
+		history.Version = uint(version)
+		history.Duration = time.Duration(duration) * time.Millisecond
+		history.RetryWait = time.Duration(retryWait) * time.Millisecond
+
+		result = append(result, &history)
+	}
+
+	return result, rows.Err()
+}
+
+func osUser() string {
+	current, err := user.Current()
+	if err == nil {
+		return current.Username
+	}
+
+	return os.Getenv("USER")
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var reVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+func interpolate(value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return nil
+		}
+
+		return interpolate(value.Elem())
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if !value.Type().Field(i).IsExported() {
+				continue
+			}
+
+			if err := interpolate(value.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := interpolate(value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			item := reflect.New(value.Type().Elem()).Elem()
+			item.Set(value.MapIndex(key))
+
+			if err := interpolate(item); err != nil {
+				return err
+			}
+
+			value.SetMapIndex(key, item)
+		}
+	case reflect.String:
+		result, err := expand(value.String())
+		if err != nil {
+			return err
+		}
+
+		value.SetString(result)
+	}
+
+	return nil
+}
+
+func expand(value string) (string, error) {
+	matches := reVariable.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value, nil
+	}
+
+	result := strings.Builder{}
+	last := 0
+	for _, match := range matches {
+		result.WriteString(value[last:match[0]])
+		last = match[1]
+
+		name := value[match[2]:match[3]]
+		env, ok := os.LookupEnv(name)
+		if ok && env != "" {
+			result.WriteString(env)
+
+			continue
+		}
+
+		if match[4] != -1 {
+			result.WriteString(value[match[6]:match[7]])
+
+			continue
+		}
+
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+	}
+
+	result.WriteString(value[last:])
+
+	return result.String(), nil
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+const REDACTED = "******"
+
+func OverlayPath(path string, env string) string {
+	ext := filepath.Ext(path)
+
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(path, ext), env, ext)
+}
+
+type loader struct {
+	documents []*document
+	problems  []*Problem
+}
+
+func (l *loader) read(path string, seen map[string]bool) (map[string]any, error) {
+	if seen[path] {
+		return nil, ConfigError("%s is included more than once", path)
+	}
+
+	seen[path] = true
+	defer delete(seen, path)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, ConfigError("%s not found", path)
+	}
+
+	// duplicate keys are reported by check, so the rest of the file is still validated
+	file, err := parser.ParseBytes(content, 0, parser.AllowDuplicateMapKey())
+	if err != nil {
+		l.syntax(path, err)
+
+		return map[string]any{}, nil
+	}
+
+	var body ast.Node
+	if len(file.Docs) > 0 {
+		body = file.Docs[0].Body
+	}
+
+	l.check(path, body, reflect.TypeOf(fileConfig{}))
+
+	values := map[string]any{}
+	if err := yaml.UnmarshalWithOptions(content, &values, yaml.AllowDuplicateMapKey()); err != nil {
+		l.syntax(path, err)
+
+		return map[string]any{}, nil
+	}
+
+	includes, err := includePaths(path, values["include"])
+	if err != nil {
+		return nil, err
+	}
+
+	delete(values, "include")
+
+	result := map[string]any{}
+	for _, include := range includes {
+		included, err := l.read(include, seen)
+		if err != nil {
+			return nil, err
+		}
+
+		result = merge(result, included)
+	}
+
+	l.documents = append(l.documents, &document{path: path, body: body})
+
+	return merge(result, values), nil
+}
+
+func includePaths(path string, include any) ([]string, error) {
+	var items []any
+	switch value := include.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		items = []any{value}
+	case []any:
+		items = value
+	default:
+		return nil, ConfigError("%s: include must be a file or a list of files", path)
+	}
+
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			return nil, ConfigError("%s: include must be a file or a list of files", path)
+		}
+
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(path), name)
+		}
+
+		paths = append(paths, name)
+	}
+
+	return paths, nil
+}
+
+func merge(base map[string]any, overlay map[string]any) map[string]any {
+	for key, value := range overlay {
+		current, ok := base[key].(map[string]any)
+		next, isMap := value.(map[string]any)
+		if ok && isMap {
+			base[key] = merge(current, next)
+
+			continue
+		}
+
+		base[key] = value
+	}
+
+	return base
+}
+
+// Redact returns the configuration as written, after includes, overlays and environment variables, with its secrets hidden.
+func Redact(config *Config) *Config {
+	content := config.source
+	if content == nil {
+		var err error
+		if content, err = yaml.Marshal(config); err != nil {
+			return &Config{}
+		}
+	}
+
+	redacted := Config{}
+	if err := yaml.Unmarshal(content, &redacted); err != nil || redacted.Migration == nil {
+		return &Config{}
+	}
+
+	for _, connection := range redacted.Migration.Connections {
+		if connection == nil {
+			continue
+		}
+
+		if connection.Password != "" {
+			connection.Password = REDACTED
+		}
+
+		if connection.URL != "" {
+			connection.URL = redactURL(connection.URL)
+		}
+
+		for key := range connection.Options {
+			if strings.Contains(strings.ToLower(key), "password") {
+				connection.Options[key] = REDACTED
+			}
+		}
+	}
+
+	return &redacted
+}
+
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return REDACTED
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), REDACTED)
+		}
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.Contains(strings.ToLower(key), "password") {
+			query.Set(key, REDACTED)
+			u.RawQuery = query.Encode()
+		}
+	}
+
+	return strings.ReplaceAll(u.String(), url.QueryEscape(REDACTED), REDACTED)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_LOCK_WAIT = "1m"
+	LOCK_NAMESPACE    = 0x6b6d74
+	LOCK_RETRY        = 500 * time.Millisecond
+
+	SQL_TRY_LOCK = "SELECT pg_try_advisory_lock($1, hashtext(current_database() || '.' || $2))"
+	SQL_UNLOCK   = "SELECT pg_advisory_unlock($1, hashtext(current_database() || '.' || $2))"
+
+	QUERY_LOCK_HOLDER = `SELECT a.pid, COALESCE(a.application_name, ''), COALESCE(a.usename, ''), COALESCE(host(a.client_addr), ''), a.query_start
+FROM pg_locks l
+JOIN pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory'
+	AND l.granted
+	AND l.database = (SELECT oid FROM pg_database WHERE datname = current_database())
+	AND l.classid = $1::int4::oid
+	AND l.objid = hashtext(current_database() || '.' || $2)::oid
+	AND l.objsubid = 2
+LIMIT 1`
+)
+
+type (
+	Lock struct {
+		conn   *sql.Conn
+		schema string
+	}
+
+	LockHolder struct {
+		Pid             int
+		ApplicationName string
+		User            string
+		ClientAddr      string
+		QueryStart      sql.NullTime
+	}
+)
+
+func (h *LockHolder) String() string {
+	details := []string{fmt.Sprintf("pid %d", h.Pid)}
+	if h.ApplicationName != "" {
+		details = append(details, fmt.Sprintf("application %s", h.ApplicationName))
+	}
+
+	if h.User != "" {
+		details = append(details, fmt.Sprintf("user %s", h.User))
+	}
+
+	if h.ClientAddr != "" {
+		details = append(details, fmt.Sprintf("client %s", h.ClientAddr))
+	}
+
+	if h.QueryStart.Valid {
+		details = append(details, fmt.Sprintf("query started %s", h.QueryStart.Time.Format(time.RFC3339)))
+	}
+
+	return strings.Join(details, ", ")
+}
+
+// AcquireLock takes the kmt advisory lock of the schema on a dedicated session.
+// A negative wait blocks until the lock is free, zero fails immediately.
+func AcquireLock(db *sql.DB, schema string, wait time.Duration) (*Lock, error) {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, ConnectionError(err)
+	}
+
+	deadline := time.Now().Add(wait)
+	reported := false
+	for {
+		locked := false
+		if err := conn.QueryRowContext(ctx, SQL_TRY_LOCK, LOCK_NAMESPACE, schema).Scan(&locked); err != nil {
+			conn.Close()
+
+			return nil, ConnectionError(err)
+		}
+
+		if locked {
+			return &Lock{conn: conn, schema: schema}, nil
+		}
+
+		holder := lockHolder(ctx, conn, schema)
+		if wait >= 0 && !time.Now().Before(deadline) {
+			conn.Close()
+
+			if holder == nil {
+				return nil, LockError("schema %s is locked by another migration", schema)
+			}
+
+			return nil, LockError("schema %s is locked by another migration (%s)", schema, holder)
+		}
+
+		if !reported {
+			reported = true
+			if holder != nil {
+				ErrorColor.Printf("Waiting for migration lock on schema %s held by %s\n", schema, holder)
+			} else {
+				ErrorColor.Printf("Waiting for migration lock on schema %s\n", schema)
+			}
+		}
+
+		time.Sleep(LOCK_RETRY)
+	}
+}
+
+func (l *Lock) Release() error {
+	defer l.conn.Close()
+
+	_, err := l.conn.ExecContext(context.Background(), SQL_UNLOCK, LOCK_NAMESPACE, l.schema)
+
+	return err
+}
+
+func lockHolder(ctx context.Context, conn *sql.Conn, schema string) *LockHolder {
+	holder := LockHolder{}
+	err := conn.QueryRowContext(ctx, QUERY_LOCK_HOLDER, LOCK_NAMESPACE, schema).Scan(&holder.Pid, &holder.ApplicationName, &holder.User, &holder.ClientAddr, &holder.QueryStart)
+	if err != nil {
+		return nil
+	}
+
+	return &holder
+}
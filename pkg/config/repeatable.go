@@ -0,0 +1,219 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	REPEATABLE_FOLDER = "repeatable"
+	REPEATABLE_TABLE  = "kmt_repeatables"
+
+	SQL_CREATE_REPEATABLE = `CREATE TABLE IF NOT EXISTS %s.%s (
+	name text PRIMARY KEY,
+	checksum text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now(),
+	duration_ms bigint NOT NULL DEFAULT 0,
+	os_user text NOT NULL DEFAULT '',
+	kmt_version text NOT NULL DEFAULT '',
+	status text NOT NULL DEFAULT 'applied'
+)`
+	SQL_UPSERT_REPEATABLE = `INSERT INTO %s.%s (name, checksum, applied_at, duration_ms, os_user, kmt_version, status) VALUES ($1, $2, now(), $3, $4, $5, $6)
+ON CONFLICT (name) DO UPDATE SET checksum = EXCLUDED.checksum, applied_at = EXCLUDED.applied_at, duration_ms = EXCLUDED.duration_ms, os_user = EXCLUDED.os_user, kmt_version = EXCLUDED.kmt_version, status = EXCLUDED.status`
+	QUERY_REPEATABLE = "SELECT name, checksum FROM %s.%s"
+)
+
+// TrackingTables are the tables kmt keeps in every migrated schema, left out of snapshots.
+var TrackingTables = []string{"schema_migrations", HISTORY_TABLE, REPEATABLE_TABLE}
+
+type Repeatable struct {
+	Name     string
+	Path     string
+	Checksum string
+
+	content []byte
+}
+
+// ListRepeatables returns the files of the repeatable folder of a schema, in name order, rendered with the vars of the connection.
+func ListRepeatables(connection *Connection, schema string, folder string) ([]*Repeatable, error) {
+	entries, err := os.ReadDir(filepath.Join(folder, REPEATABLE_FOLDER))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	repeatables := []*Repeatable{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		path := filepath.Join(folder, REPEATABLE_FOLDER, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err = Render(filepath.Join(REPEATABLE_FOLDER, entry.Name()), content, connection.SchemaVars(schema))
+		if err != nil {
+			return nil, err
+		}
+
+		repeatables = append(repeatables, &Repeatable{Name: entry.Name(), Path: path, Checksum: checksum(content), content: content})
+	}
+
+	return repeatables, nil
+}
+
+// PendingRepeatables returns the repeatable files whose rendered content is new or changed since they were last applied.
+func PendingRepeatables(db *sql.DB, connection *Connection, schema string, folder string) ([]*Repeatable, error) {
+	repeatables, err := ListRepeatables(connection, schema, folder)
+	if err != nil || len(repeatables) == 0 {
+		return nil, err
+	}
+
+	applied := map[string]string{}
+	rows, err := db.Query(fmt.Sprintf(QUERY_REPEATABLE, schema, REPEATABLE_TABLE))
+	switch code := PgCode(err); {
+	case code == "42P01" || code == "3F000":
+	case err != nil:
+		return nil, err
+	default:
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, checksum string
+			if err := rows.Scan(&name, &checksum); err != nil {
+				return nil, err
+			}
+
+			applied[name] = checksum
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	pending := []*Repeatable{}
+	for _, repeatable := range repeatables {
+		if applied[repeatable.Name] != repeatable.Checksum {
+			pending = append(pending, repeatable)
+		}
+	}
+
+	return pending, nil
+}
+
+// ApplyRepeatables runs the pending repeatable files of a schema, each one in its own transaction.
+func ApplyRepeatables(db *sql.DB, connection *Connection, schema string, folder string) (int, error) {
+	pending, err := PendingRepeatables(db, connection, schema, folder)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, ConnectionError(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(SQL_CREATE_REPEATABLE, schema, REPEATABLE_TABLE)); err != nil {
+		return 0, err
+	}
+
+	limits, _ := schemaLimits(connection, schema)
+	defer func() {
+		if reset, err := (timeouts{}).sql(); err == nil {
+			conn.ExecContext(ctx, reset)
+		}
+	}()
+
+	for i, repeatable := range pending {
+		if err := applyRepeatable(ctx, conn, connection, schema, limits, repeatable); err != nil {
+			return i, err
+		}
+	}
+
+	return len(pending), nil
+}
+
+func applyRepeatable(ctx context.Context, conn *sql.Conn, connection *Connection, schema string, limits timeouts, repeatable *Repeatable) error {
+	name := filepath.Join(REPEATABLE_FOLDER, repeatable.Name)
+	content := repeatable.content
+
+	header, err := ParseHeader(content)
+	if err != nil {
+		return fmt.Errorf("migration %s %s", name, err.Error())
+	}
+
+	upsert := fmt.Sprintf(SQL_UPSERT_REPEATABLE, schema, REPEATABLE_TABLE)
+	if reason := header.Skipped(connection); reason != "" {
+		BoldColor.Printf("Repeatable %s %s, recorded without running\n", name, reason)
+
+		_, err := conn.ExecContext(ctx, upsert, repeatable.Name, repeatable.Checksum, 0, osUser(), VERSION_STRING, HISTORY_SKIPPED)
+
+		return err
+	}
+
+	limits = limits.override(header)
+	session, err := limits.sql()
+	if err != nil {
+		return fmt.Errorf("migration %s %s", name, err.Error())
+	}
+
+	if _, err := conn.ExecContext(ctx, session); err != nil {
+		return err
+	}
+
+	started := time.Now()
+	if _, ok := header[HEADER_NO_TRANSACTION]; ok {
+		statements := SplitStatements(string(content))
+		for i, statement := range statements {
+			if _, err := conn.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("migration %s runs without transaction and failed at statement %d of %d, the %d statement(s) before it stay applied: %w", name, i+1, len(statements), i, limits.explain(name, err))
+			}
+		}
+
+		_, err := conn.ExecContext(ctx, upsert, repeatable.Name, repeatable.Checksum, time.Since(started).Milliseconds(), osUser(), VERSION_STRING, HISTORY_APPLIED)
+
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(content)); err != nil {
+		return limits.explain(name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, upsert, repeatable.Name, repeatable.Checksum, time.Since(started).Milliseconds(), osUser(), VERSION_STRING, HISTORY_APPLIED); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	SuccessColor.Printf("Repeatable %s applied\n", name)
+
+	return nil
+}
+
+// Content returns the rendered script of the repeatable file.
+func (r *Repeatable) Content() []byte {
+	return r.content
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListRepeatablesChecksumsRenderedContent(t *testing.T) {
+	folder := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(folder, REPEATABLE_FOLDER), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "-- kmt:template\nCREATE OR REPLACE VIEW orders_view AS SELECT * FROM orders;\nALTER VIEW orders_view OWNER TO {{.owner}};\n"
+	if err := os.WriteFile(filepath.Join(folder, REPEATABLE_FOLDER, "view_orders.sql"), []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	checksums := map[string]string{}
+	for _, owner := range []string{"app", "report"} {
+		repeatables, err := ListRepeatables(&Connection{Vars: map[string]string{"owner": owner}}, "public", folder)
+		if err != nil {
+			t.Fatalf("ListRepeatables() error = %v", err)
+		}
+
+		if len(repeatables) != 1 {
+			t.Fatalf("ListRepeatables() returned %d file(s), want 1", len(repeatables))
+		}
+
+		checksums[owner] = repeatables[0].Checksum
+	}
+
+	if checksums["app"] == checksums["report"] {
+		t.Errorf("checksum %s is the same for different vars", checksums["app"])
+	}
+}
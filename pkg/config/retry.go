@@ -0,0 +1,46 @@
+package config
+
+import (
+	"time"
+)
+
+const (
+	DEFAULT_RETRY_ATTEMPTS = 3
+	DEFAULT_RETRY_BACKOFF  = "1s"
+	MAX_RETRY_BACKOFF      = time.Minute
+)
+
+func (r *Retry) inherit(parent *Retry) *Retry {
+	if r == nil {
+		return parent
+	}
+
+	if parent == nil {
+		return r
+	}
+
+	retry := *r
+	if retry.Attempts == 0 {
+		retry.Attempts = parent.Attempts
+	}
+
+	if retry.Backoff == "" {
+		retry.Backoff = parent.Backoff
+	}
+
+	return &retry
+}
+
+// Wait returns how long to sleep before the next attempt, doubling the backoff after every failed attempt.
+func (r *Retry) Wait(attempt int) time.Duration {
+	backoff, err := ParseTimeout(r.Backoff)
+	if err != nil {
+		return 0
+	}
+
+	for i := 1; i < attempt && backoff < MAX_RETRY_BACKOFF; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, MAX_RETRY_BACKOFF)
+}
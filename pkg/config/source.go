@@ -0,0 +1,344 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	LAYOUT_SPLIT  = "split"
+	LAYOUT_SINGLE = "single"
+
+	SECTION_UP   = "-- +kmt Up"
+	SECTION_DOWN = "-- +kmt Down"
+)
+
+var (
+	reMigration = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down)\.sql$`)
+	reSingle    = regexp.MustCompile(`^([0-9]+)_(.*)\.sql$`)
+)
+
+type MigrationFile struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+	Single  bool
+}
+
+func init() {
+	source.Register("kmt", &sourceDriver{})
+}
+
+func ListMigrations(folder string) ([]*MigrationFile, error) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[uint]*MigrationFile)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		single := false
+		matches := reMigration.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			single = true
+			matches = reSingle.FindStringSubmatch(entry.Name())
+		}
+
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(folder, entry.Name())
+		file, ok := files[uint(version)]
+		if ok && (single || file.Single) {
+			existing := file.Up
+			if existing == "" {
+				existing = file.Down
+			}
+
+			return nil, ConfigError("migration version %d is defined by both %s and %s", version, filepath.Base(existing), entry.Name())
+		}
+
+		if !ok {
+			file = &MigrationFile{Version: uint(version), Name: matches[2], Single: single}
+			files[uint(version)] = file
+		}
+
+		switch {
+		case single:
+			file.Up = path
+			file.Down = path
+		case matches[3] == "up":
+			file.Up = path
+		default:
+			file.Down = path
+		}
+	}
+
+	result := make([]*MigrationFile, 0, len(files))
+	for _, file := range files {
+		result = append(result, file)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+func (f *MigrationFile) Path(up bool) string {
+	if up {
+		return f.Up
+	}
+
+	return f.Down
+}
+
+// Read returns the script of one direction, the matching section for a single-file migration.
+func (f *MigrationFile) Read(up bool) ([]byte, error) {
+	path := f.Path(up)
+	if path == "" {
+		return nil, &os.PathError{Op: "read", Path: fmt.Sprintf("%d_%s", f.Version, f.Name), Err: os.ErrNotExist}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil || !f.Single {
+		return content, err
+	}
+
+	upScript, downScript, err := ParseSections(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	if up {
+		return upScript, nil
+	}
+
+	return downScript, nil
+}
+
+func (f *MigrationFile) Checksum(up bool) (string, error) {
+	body, err := f.Read(up)
+	if err != nil {
+		return "", err
+	}
+
+	return checksum(body), nil
+}
+
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseSections splits a single-file migration on its -- +kmt Up and -- +kmt Down markers.
+// The newline that ends the up section separates it from the down marker and isn't part of the script.
+func ParseSections(content []byte) ([]byte, []byte, error) {
+	var (
+		up, down bytes.Buffer
+		current  *bytes.Buffer
+		seenUp   bool
+		seenDown bool
+	)
+
+	for number, line := range bytes.SplitAfter(content, []byte("\n")) {
+		trimmed := strings.TrimSpace(string(line))
+
+		switch {
+		case strings.EqualFold(trimmed, SECTION_UP):
+			if seenUp {
+				return nil, nil, fmt.Errorf("line %d: duplicate %s marker", number+1, SECTION_UP)
+			}
+
+			seenUp, current = true, &up
+
+			continue
+		case strings.EqualFold(trimmed, SECTION_DOWN):
+			if seenDown {
+				return nil, nil, fmt.Errorf("line %d: duplicate %s marker", number+1, SECTION_DOWN)
+			}
+
+			seenDown, current = true, &down
+
+			continue
+		case current == nil:
+			if strings.HasPrefix(trimmed, HEADER_PREFIX) {
+				return nil, nil, fmt.Errorf("line %d: header %s must be inside the %s or %s section it applies to", number+1, trimmed, SECTION_UP, SECTION_DOWN)
+			}
+
+			if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return nil, nil, fmt.Errorf("line %d: statement outside of a %s or %s section", number+1, SECTION_UP, SECTION_DOWN)
+			}
+
+			continue
+		}
+
+		current.Write(line)
+	}
+
+	if !seenUp {
+		return nil, nil, fmt.Errorf("missing %s marker", SECTION_UP)
+	}
+
+	return bytes.TrimSuffix(up.Bytes(), []byte("\n")), down.Bytes(), nil
+}
+
+// FormatSections joins the up and down scripts into a single file that ParseSections reads back byte for byte.
+func FormatSections(up string, down string) string {
+	return SECTION_UP + "\n" + up + "\n" + SECTION_DOWN + "\n" + down
+}
+
+// WriteMigration writes the up and down scripts of a migration in the given layout.
+func WriteMigration(folder string, name string, up string, down string, layout string) ([]string, error) {
+	if layout == LAYOUT_SINGLE {
+		path := filepath.Join(folder, name+".sql")
+
+		return []string{path}, os.WriteFile(path, []byte(FormatSections(up, down)), 0666)
+	}
+
+	paths := []string{filepath.Join(folder, name+".up.sql"), filepath.Join(folder, name+".down.sql")}
+	if err := os.WriteFile(paths[0], []byte(up), 0666); err != nil {
+		return nil, err
+	}
+
+	return paths, os.WriteFile(paths[1], []byte(down), 0666)
+}
+
+func Checksum(path string) (string, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func CurrentVersion(db *sql.DB, schema string) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := db.QueryRow(fmt.Sprintf("SELECT version, dirty FROM %s.schema_migrations LIMIT 1", schema)).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "42P01" || pgErr.Code == "3F000") {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if version < 0 {
+		return 0, dirty, nil
+	}
+
+	return uint(version), dirty, nil
+}
+
+type sourceDriver struct {
+	folder   string
+	versions []uint
+	files    map[uint]*MigrationFile
+}
+
+func (s *sourceDriver) Open(url string) (source.Driver, error) {
+	folder := strings.TrimPrefix(url, "kmt://")
+
+	files, err := ListMigrations(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	driver := &sourceDriver{folder: folder, files: migrationVersions(files)}
+	for _, file := range files {
+		driver.versions = append(driver.versions, file.Version)
+	}
+
+	return driver, nil
+}
+
+func (s *sourceDriver) Close() error {
+	return nil
+}
+
+func (s *sourceDriver) First() (uint, error) {
+	if len(s.versions) == 0 {
+		return 0, &os.PathError{Op: "first", Path: s.folder, Err: os.ErrNotExist}
+	}
+
+	return s.versions[0], nil
+}
+
+func (s *sourceDriver) Prev(version uint) (uint, error) {
+	i := sort.Search(len(s.versions), func(i int) bool { return s.versions[i] >= version })
+	if i == 0 || i == len(s.versions) || s.versions[i] != version {
+		return 0, &os.PathError{Op: fmt.Sprintf("prev for version %d", version), Path: s.folder, Err: os.ErrNotExist}
+	}
+
+	return s.versions[i-1], nil
+}
+
+func (s *sourceDriver) Next(version uint) (uint, error) {
+	i := sort.Search(len(s.versions), func(i int) bool { return s.versions[i] > version })
+	if i == len(s.versions) {
+		return 0, &os.PathError{Op: fmt.Sprintf("next for version %d", version), Path: s.folder, Err: os.ErrNotExist}
+	}
+
+	return s.versions[i], nil
+}
+
+func (s *sourceDriver) ReadUp(version uint) (io.ReadCloser, string, error) {
+	return s.read(version, true)
+}
+
+func (s *sourceDriver) ReadDown(version uint) (io.ReadCloser, string, error) {
+	return s.read(version, false)
+}
+
+func (s *sourceDriver) read(version uint, up bool) (io.ReadCloser, string, error) {
+	file, ok := s.files[version]
+	if !ok || file.Path(up) == "" {
+		return nil, "", &os.PathError{Op: fmt.Sprintf("read version %d", version), Path: s.folder, Err: os.ErrNotExist}
+	}
+
+	body, err := file.Read(up)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return io.NopCloser(bytes.NewReader(body)), file.Name, nil
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseSectionsRoundTrip(t *testing.T) {
+	for _, scripts := range [][2]string{{"CREATE TABLE a ();", "DROP TABLE a;"}, {"CREATE TABLE a ();\n", "DROP TABLE a;\n"}, {"", ""}} {
+		up, down, err := ParseSections([]byte(FormatSections(scripts[0], scripts[1])))
+		if err != nil {
+			t.Fatalf("ParseSections() error = %v", err)
+		}
+
+		if string(up) != scripts[0] || string(down) != scripts[1] {
+			t.Errorf("ParseSections() = %q, %q, want %q, %q", up, down, scripts[0], scripts[1])
+		}
+	}
+}
+
+func TestParseSectionsRejectsHeaderAboveUp(t *testing.T) {
+	content := []byte("-- kmt:no-transaction\n-- +kmt Up\nCREATE INDEX CONCURRENTLY a_b ON a (b);\n-- +kmt Down\nDROP INDEX a_b;\n")
+
+	if _, _, err := ParseSections(content); err == nil {
+		t.Error("ParseSections() error = nil, want header outside of a section error")
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"strings"
+)
+
+// SplitStatements splits a SQL script on semicolons outside of quotes, dollar quotes and comments.
+func SplitStatements(script string) []string {
+	statements := []string{}
+	current := strings.Builder{}
+	code := false
+
+	flush := func() {
+		if code {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+
+		current.Reset()
+		code = false
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+
+		switch {
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+
+			current.WriteString(script[i : i+end])
+			i += end - 1
+
+			continue
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := blockCommentEnd(script, i)
+			current.WriteString(script[i:end])
+			i = end - 1
+
+			continue
+		case c == '\'' || c == '"':
+			end := quoteEnd(script, i, c, c == '\'' && i > 0 && (script[i-1] == 'E' || script[i-1] == 'e'))
+			current.WriteString(script[i:end])
+			i = end - 1
+			code = true
+
+			continue
+		case c == '$':
+			if tag := dollarTag(script[i:]); tag != "" {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					end = len(script)
+				} else {
+					end += i + 2*len(tag)
+				}
+
+				current.WriteString(script[i:end])
+				i = end - 1
+				code = true
+
+				continue
+			}
+		case c == ';':
+			current.WriteByte(c)
+			flush()
+
+			continue
+		}
+
+		current.WriteByte(c)
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			code = true
+		}
+	}
+
+	flush()
+
+	return statements
+}
+
+func blockCommentEnd(script string, start int) int {
+	depth := 0
+	for i := start; i < len(script)-1; i++ {
+		switch script[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(script)
+}
+
+func quoteEnd(script string, start int, quote byte, escapes bool) int {
+	for i := start + 1; i < len(script); i++ {
+		switch {
+		case escapes && script[i] == '\\':
+			i++
+		case script[i] == quote:
+			if i+1 < len(script) && script[i+1] == quote {
+				i++
+
+				continue
+			}
+
+			return i + 1
+		}
+	}
+
+	return len(script)
+}
+
+func dollarTag(script string) string {
+	for i := 1; i < len(script); i++ {
+		c := script[i]
+		if c == '$' {
+			return script[:i+1]
+		}
+
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9') {
+			return ""
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+var reVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Render executes a migration with a -- kmt:template header as a text/template with the vars of its connection and schema.
+// Other files are returned as written. A variable missing from vars is an error, also on a connection without vars.
+func Render(name string, content []byte, vars map[string]string) ([]byte, error) {
+	header, err := ParseHeader(content)
+	if err != nil {
+		return nil, fmt.Errorf("migration %s %s", name, err.Error())
+	}
+
+	if _, ok := header[HEADER_TEMPLATE]; !ok {
+		return content, nil
+	}
+
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, vars); err != nil {
+		return nil, err
+	}
+
+	return rendered.Bytes(), nil
+}
+
+func (c *Connection) SchemaVars(schema string) map[string]string {
+	if settings, ok := c.Schemas[schema]; ok && settings != nil {
+		return settings.Vars
+	}
+
+	return c.Vars
+}
+
+// RenderedChecksum returns the checksum of the script that runs on the connection, after its template is rendered.
+func (f *MigrationFile) RenderedChecksum(up bool, connection *Connection, schema string) (string, error) {
+	body, err := f.Read(up)
+	if err != nil {
+		return "", err
+	}
+
+	body, err = Render(filepath.Base(f.Path(up)), body, connection.SchemaVars(schema))
+	if err != nil {
+		return "", err
+	}
+
+	return checksum(body), nil
+}
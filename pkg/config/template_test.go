@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestRenderKeepsFilesWithoutTemplateHeader(t *testing.T) {
+	content := []byte("INSERT INTO matrix (cells) VALUES ('{{1,2},{3,4}}');\n")
+
+	rendered, err := Render("1700000000_matrix.up.sql", content, nil)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if string(rendered) != string(content) {
+		t.Errorf("Render() = %q, want %q", rendered, content)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	content := []byte("-- kmt:template\nALTER TABLE orders OWNER TO {{.owner}};\nSELECT '{{\"{{\"}}1,2},{3,4}}';\n")
+
+	rendered, err := Render("1700000000_owner.up.sql", content, map[string]string{"owner": "app"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	want := "-- kmt:template\nALTER TABLE orders OWNER TO app;\nSELECT '{{1,2},{3,4}}';\n"
+	if string(rendered) != want {
+		t.Errorf("Render() = %q, want %q", rendered, want)
+	}
+
+	if _, err := Render("1700000000_owner.up.sql", content, nil); err == nil {
+		t.Error("Render() without vars error = nil, want missing variable error")
+	}
+}
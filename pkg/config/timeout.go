@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	SQL_SET_LOCK_TIMEOUT        = "SET lock_timeout = %d"
+	SQL_SET_STATEMENT_TIMEOUT   = "SET statement_timeout = %d"
+	SQL_RESET_LOCK_TIMEOUT      = "RESET lock_timeout"
+	SQL_RESET_STATEMENT_TIMEOUT = "RESET statement_timeout"
+
+	PG_LOCK_NOT_AVAILABLE = "55P03"
+	PG_QUERY_CANCELED     = "57014"
+)
+
+type timeouts struct {
+	lock      string
+	statement string
+}
+
+func (t timeouts) override(header Header) timeouts {
+	if value, ok := header[HEADER_LOCK_TIMEOUT]; ok {
+		t.lock = value
+	}
+
+	if value, ok := header[HEADER_STATEMENT_TIMEOUT]; ok {
+		t.statement = value
+	}
+
+	return t
+}
+
+func (t timeouts) sql() (string, error) {
+	lock, err := timeoutSql(HEADER_LOCK_TIMEOUT, t.lock, SQL_SET_LOCK_TIMEOUT, SQL_RESET_LOCK_TIMEOUT)
+	if err != nil {
+		return "", err
+	}
+
+	statement, err := timeoutSql(HEADER_STATEMENT_TIMEOUT, t.statement, SQL_SET_STATEMENT_TIMEOUT, SQL_RESET_STATEMENT_TIMEOUT)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s; %s", lock, statement), nil
+}
+
+// explain names the timeout that aborted a migration, the original error stays in the chain.
+func (t timeouts) explain(name string, err error) error {
+	switch PgCode(err) {
+	case PG_LOCK_NOT_AVAILABLE:
+		return fmt.Errorf("migration %s aborted, lock_timeout %s expired while waiting for a lock: %w", name, t.lock, err)
+	case PG_QUERY_CANCELED:
+		if t.statement != "" {
+			return fmt.Errorf("migration %s aborted, statement_timeout %s expired: %w", name, t.statement, err)
+		}
+	}
+
+	return err
+}
+
+func timeoutSql(name string, value string, set string, reset string) (string, error) {
+	if value == "" {
+		return reset, nil
+	}
+
+	duration, err := ParseTimeout(value)
+	if err != nil {
+		return "", fmt.Errorf("%s %s", name, err.Error())
+	}
+
+	return fmt.Sprintf(set, duration.Milliseconds()), nil
+}
+
+func ParseTimeout(value string) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%s is not a valid duration, e.g. 5s or 1m", value)
+	}
+
+	return duration, nil
+}
+
+// PgCode returns the SQLSTATE of a Postgres error, including errors wrapped by the migrate driver.
+func PgCode(err error) string {
+	var dbErr database.Error
+	if errors.As(err, &dbErr) {
+		err = dbErr.OrigErr
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+
+	return ""
+}
@@ -0,0 +1,444 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/token"
+)
+
+type (
+	Problem struct {
+		File    string `yaml:"file" json:"file"`
+		Line    int    `yaml:"line" json:"line"`
+		Column  int    `yaml:"column" json:"column"`
+		Message string `yaml:"message" json:"message"`
+	}
+
+	ValidationError struct {
+		Problems []*Problem
+	}
+
+	document struct {
+		path string
+		body ast.Node
+	}
+
+	fileConfig struct {
+		Include   any        `yaml:"include"`
+		Migration *Migration `yaml:"migration"`
+	}
+)
+
+func (p *Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		lines = append(lines, problem.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (l *loader) err() error {
+	if len(l.problems) == 0 {
+		return nil
+	}
+
+	return NewExitError(EXIT_CONFIG, &ValidationError{Problems: l.problems})
+}
+
+// errOr returns the problems found so far, or err when there are none.
+func (l *loader) errOr(err error) error {
+	if problems := l.err(); problems != nil {
+		return problems
+	}
+
+	return err
+}
+
+func (l *loader) add(path string, tk *token.Token, format string, a ...any) {
+	problem := &Problem{File: displayPath(path), Message: fmt.Sprintf(format, a...)}
+	if tk != nil {
+		problem.Line = tk.Position.Line
+		problem.Column = tk.Position.Column
+	}
+
+	l.problems = append(l.problems, problem)
+}
+
+func (l *loader) syntax(path string, err error) {
+	var yamlErr yaml.Error
+	if errors.As(err, &yamlErr) {
+		l.add(path, yamlErr.GetToken(), "%s", yamlErr.GetMessage())
+
+		return
+	}
+
+	l.add(path, nil, "%s", err.Error())
+}
+
+func (l *loader) check(path string, node ast.Node, typ reflect.Type) {
+	node = unwrap(node)
+	if node == nil || node.Type() == ast.NullType {
+		return
+	}
+
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Map:
+		values, ok := mappingValues(node)
+		if !ok {
+			l.add(path, node.GetToken(), "expected a mapping")
+
+			return
+		}
+
+		seen := map[string]*token.Token{}
+		for _, value := range values {
+			if value.Key.IsMergeKey() {
+				continue
+			}
+
+			key := value.Key.GetToken()
+			if first, ok := seen[key.Value]; ok {
+				l.add(path, key, "duplicate key '%s', first defined at line %d", key.Value, first.Position.Line)
+			}
+
+			seen[key.Value] = key
+
+			if typ.Kind() == reflect.Map {
+				l.check(path, value.Value, typ.Elem())
+
+				continue
+			}
+
+			field, ok := fieldByName(typ, key.Value)
+			if !ok {
+				l.add(path, key, "unknown field '%s', expected one of %s", key.Value, strings.Join(fieldNames(typ), ", "))
+
+				continue
+			}
+
+			l.check(path, value.Value, field.Type)
+		}
+	case reflect.Slice:
+		sequence, ok := node.(*ast.SequenceNode)
+		if !ok {
+			l.add(path, node.GetToken(), "expected a list")
+
+			return
+		}
+
+		for _, value := range sequence.Values {
+			l.check(path, value, typ.Elem())
+		}
+	case reflect.String:
+		if _, ok := node.(ast.ScalarNode); !ok {
+			l.add(path, node.GetToken(), "expected a string")
+		}
+	case reflect.Int:
+		if _, ok := node.(*ast.IntegerNode); !ok {
+			l.add(path, node.GetToken(), "expected a number")
+		}
+	}
+}
+
+func (l *loader) validate(path string, config *Config) {
+	connections := make([]string, 0, len(config.Migration.Connections))
+	for name := range config.Migration.Connections {
+		connections = append(connections, name)
+	}
+
+	sort.Strings(connections)
+
+	for _, name := range connections {
+		connection := config.Migration.Connections[name]
+		if connection == nil {
+			l.locate(path, "connection '%s' is empty", []any{"migration", "connections", name}, name)
+
+			continue
+		}
+
+		switch {
+		case connection.URL != "":
+			if connection.Host != "" || connection.Port != 0 || connection.Name != "" || connection.User != "" || connection.Service != "" {
+				l.locate(path, "connection '%s' url cannot be combined with host, port, name, user or service", []any{"migration", "connections", name, "url"}, name)
+			}
+
+			if _, err := connection.urlDSN(); err != nil {
+				l.locate(path, "connection '%s' %s", []any{"migration", "connections", name, "url"}, name, err.Error())
+			}
+		case connection.Service == "":
+			for _, field := range []struct{ key, value string }{{"host", connection.Host}, {"name", connection.Name}, {"user", connection.User}} {
+				if field.value == "" {
+					l.locate(path, "connection '%s' is missing %s", []any{"migration", "connections", name}, name, field.key)
+				}
+			}
+
+			if connection.Port == 0 {
+				l.locate(path, "connection '%s' is missing port", []any{"migration", "connections", name}, name)
+			}
+		}
+
+		if connection.Port < 0 || connection.Port > 65535 {
+			l.locate(path, "connection '%s' port %d is out of range", []any{"migration", "connections", name, "port"}, name, connection.Port)
+		}
+
+		if len(connection.Schemas) == 0 {
+			l.locate(path, "connection '%s' has no schemas", []any{"migration", "connections", name}, name)
+		}
+
+		l.timeouts(path, connection.LockTimeout, connection.StatementTimeout, "migration", "connections", name)
+		l.retry(path, connection.Retry, "migration", "connections", name, "retry")
+		l.vars(path, connection.Vars, "migration", "connections", name, "vars")
+		for _, schema := range slices.Sorted(maps.Keys(connection.Schemas)) {
+			if settings := connection.Schemas[schema]; settings != nil {
+				l.timeouts(path, settings.LockTimeout, settings.StatementTimeout, "migration", "connections", name, "schemas", schema)
+				l.retry(path, settings.Retry, "migration", "connections", name, "schemas", schema, "retry")
+				l.vars(path, settings.Vars, "migration", "connections", name, "schemas", schema, "vars")
+			}
+		}
+	}
+
+	clusters := make([]string, 0, len(config.Migration.Clusters))
+	for name := range config.Migration.Clusters {
+		clusters = append(clusters, name)
+	}
+
+	sort.Strings(clusters)
+
+	for _, name := range clusters {
+		members := config.Migration.Clusters[name]
+		if len(members) == 0 {
+			l.locate(path, "cluster '%s' has no connections", []any{"migration", "clusters", name}, name)
+
+			continue
+		}
+
+		for i, member := range members {
+			if _, ok := config.Migration.Connections[member]; !ok {
+				l.locate(path, "cluster '%s' references undefined connection '%s'", []any{"migration", "clusters", name, i}, name, member)
+			}
+
+			if slices.Index(members, member) != i {
+				l.locate(path, "cluster '%s' lists connection '%s' more than once", []any{"migration", "clusters", name, i}, name, member)
+			}
+		}
+	}
+
+	if wait, err := time.ParseDuration(config.Migration.LockWait); err != nil || wait < 0 {
+		l.locate(path, "lock_wait %s is not a valid duration, e.g. 30s or 5m", []any{"migration", "lock_wait"}, config.Migration.LockWait)
+	}
+
+	if config.Migration.Layout != LAYOUT_SPLIT && config.Migration.Layout != LAYOUT_SINGLE {
+		l.locate(path, "layout %s is not supported, expected %s or %s", []any{"migration", "layout"}, config.Migration.Layout, LAYOUT_SPLIT, LAYOUT_SINGLE)
+	}
+
+	l.timeouts(path, config.Migration.LockTimeout, config.Migration.StatementTimeout, "migration")
+	l.retry(path, config.Migration.Retry, "migration", "retry")
+
+	if info, err := os.Stat(config.Migration.Folder); err == nil && !info.IsDir() {
+		l.locate(path, "folder %s is not a directory", []any{"migration", "folder"}, config.Migration.Folder)
+	}
+}
+
+func (l *loader) timeouts(path string, lock string, statement string, keys ...any) {
+	for _, setting := range []struct{ key, value string }{{HEADER_LOCK_TIMEOUT, lock}, {HEADER_STATEMENT_TIMEOUT, statement}} {
+		if setting.value == "" {
+			continue
+		}
+
+		if _, err := ParseTimeout(setting.value); err != nil {
+			l.locate(path, "%s %s", append(slices.Clone(keys), setting.key), setting.key, err.Error())
+		}
+	}
+}
+
+func (l *loader) retry(path string, retry *Retry, keys ...any) {
+	if retry == nil {
+		return
+	}
+
+	attempts := append(slices.Clone(keys), "attempts")
+	if retry.Attempts < 1 && (retry.Attempts < 0 || l.defined(attempts)) {
+		l.locate(path, "retry attempts %d must be at least 1", attempts, retry.Attempts)
+	}
+
+	if retry.Backoff == "" {
+		return
+	}
+
+	if _, err := ParseTimeout(retry.Backoff); err != nil {
+		l.locate(path, "retry backoff %s", append(slices.Clone(keys), "backoff"), err.Error())
+	}
+}
+
+func (l *loader) vars(path string, vars map[string]string, keys ...any) {
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		if !reVarName.MatchString(name) {
+			l.locate(path, "variable name '%s' must start with a letter or underscore and contain only letters, digits and underscores", append(slices.Clone(keys), name), name)
+		}
+	}
+}
+
+func (l *loader) locate(path string, format string, keys []any, a ...any) {
+	for i := len(l.documents) - 1; i >= 0; i-- {
+		if tk := lookup(l.documents[i].body, keys); tk != nil {
+			l.add(l.documents[i].path, tk, format, a...)
+
+			return
+		}
+	}
+
+	l.add(path, nil, format, a...)
+}
+
+// defined reports whether keys are written in any loaded document, as a zero value can't tell.
+func (l *loader) defined(keys []any) bool {
+	for _, document := range l.documents {
+		if lookup(document.body, keys) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lookup(node ast.Node, keys []any) *token.Token {
+	var tk *token.Token
+	for _, key := range keys {
+		node = unwrap(node)
+		if node == nil {
+			return nil
+		}
+
+		switch key := key.(type) {
+		case string:
+			values, ok := mappingValues(node)
+			if !ok {
+				return nil
+			}
+
+			found := false
+			for _, value := range values {
+				if value.Key.GetToken().Value == key {
+					node, tk, found = value.Value, value.Key.GetToken(), true
+
+					break
+				}
+			}
+
+			if !found {
+				return nil
+			}
+		case int:
+			sequence, ok := node.(*ast.SequenceNode)
+			if !ok || key >= len(sequence.Values) {
+				return nil
+			}
+
+			node = unwrap(sequence.Values[key])
+			if node == nil {
+				return nil
+			}
+
+			tk = node.GetToken()
+		}
+	}
+
+	return tk
+}
+
+func unwrap(node ast.Node) ast.Node {
+	for {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			node = n.Value
+		case *ast.TagNode:
+			node = n.Value
+		case *ast.AliasNode:
+			return nil
+		default:
+			return node
+		}
+	}
+}
+
+func mappingValues(node ast.Node) ([]*ast.MappingValueNode, bool) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		return n.Values, true
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}, true
+	}
+
+	return nil, false
+}
+
+func fieldByName(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if tagName(field) == name && name != "-" {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func fieldNames(typ reflect.Type) []string {
+	names := make([]string, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		if name := tagName(typ.Field(i)); name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func tagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+
+	return name
+}
+
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+
+	return rel
+}
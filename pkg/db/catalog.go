@@ -0,0 +1,463 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type (
+	catalog struct {
+		db *sql.DB
+	}
+
+	catalogColumn struct {
+		Name         string
+		DataType     string
+		Collation    string
+		DefaultValue string
+		Identity     string
+		Generated    string
+		NotNull      bool
+	}
+
+	catalogSequence struct {
+		Name        string
+		Column      string
+		DataType    string
+		StartValue  int64
+		IncrementBy int64
+		MinValue    int64
+		MaxValue    int64
+		CacheSize   int64
+		Cycle       bool
+		Owned       bool
+	}
+
+	catalogPartition struct {
+		Key    string
+		Parent string
+		Bound  string
+	}
+
+	catalogObject struct {
+		Name       string
+		Type       string
+		Definition string
+	}
+)
+
+func NewCatalog(db *sql.DB) *catalog {
+	return &catalog{db: db}
+}
+
+func (c *catalog) Generate(name string, schemaOnly bool) (*Ddl, error) {
+	ddl := &Ddl{
+		Name:       strings.ReplaceAll(name, ".", "_"),
+		Definition: &Migration{},
+		Insert:     &Migration{},
+		Reference:  &Migration{},
+		ForeignKey: &Migration{},
+		index:      &Migration{},
+	}
+
+	tables := strings.Split(name, ".")
+	if len(tables) != 2 {
+		return nil, fmt.Errorf("invalid table name %s", name)
+	}
+
+	schema, table := tables[0], tables[1]
+	qualified, err := c.qualifiedName(schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := c.columns(schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	sequences, err := c.sequences(schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	partition, err := c.partition(schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	constraints, err := c.objects(QUERY_CATALOG_CONSTRAINT, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := c.objects(QUERY_CATALOG_INDEX, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var upScript strings.Builder
+	var downScript strings.Builder
+	var upReferenceScript strings.Builder
+	var downReferenceScript strings.Builder
+	var upForeignScript strings.Builder
+	var downForeignScript strings.Builder
+	var upIndexScript strings.Builder
+	var downIndexScript strings.Builder
+
+	for _, sequence := range sequences {
+		var cycle string
+		if sequence.Cycle {
+			cycle = "\n    CYCLE"
+		}
+
+		upScript.WriteString(fmt.Sprintf(
+			SQL_CREATE_SEQUENCE,
+			sequence.Name,
+			sequence.DataType,
+			sequence.StartValue,
+			sequence.IncrementBy,
+			sequence.MinValue,
+			sequence.MaxValue,
+			sequence.CacheSize,
+			cycle,
+		))
+		upScript.WriteString("\n\n")
+	}
+
+	definitions := make([]string, 0, len(columns))
+	if partition.Parent == "" {
+		for _, column := range columns {
+			definitions = append(definitions, c.columnDefinition(column))
+		}
+	}
+
+	for _, constraint := range constraints {
+		switch constraint.Type {
+		case "c":
+			definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s %s", constraint.Name, constraint.Definition))
+		case "f":
+			upForeignScript.WriteString(fmt.Sprintf(SQL_ADD_CONSTRAINT, qualified, constraint.Name, constraint.Definition))
+			upForeignScript.WriteString("\n")
+			downForeignScript.WriteString(fmt.Sprintf(SECURE_DROP_CONSTRAINT, qualified, constraint.Name))
+			downForeignScript.WriteString("\n")
+		default:
+			upReferenceScript.WriteString(fmt.Sprintf(SQL_ADD_CONSTRAINT, qualified, constraint.Name, constraint.Definition))
+			upReferenceScript.WriteString("\n")
+			downReferenceScript.WriteString(fmt.Sprintf(SECURE_DROP_CONSTRAINT, qualified, constraint.Name))
+			downReferenceScript.WriteString("\n")
+		}
+	}
+
+	upScript.WriteString(c.createTable(qualified, definitions, partition))
+
+	for _, sequence := range sequences {
+		if !sequence.Owned {
+			continue
+		}
+
+		upScript.WriteString("\n")
+		upScript.WriteString(fmt.Sprintf(SQL_SEQUENCE_OWNED_BY, sequence.Name, qualified, sequence.Column))
+		upScript.WriteString("\n")
+	}
+
+	for _, index := range indexes {
+		upIndexScript.WriteString("\n")
+		upIndexScript.WriteString(index.Definition)
+		upIndexScript.WriteString(";\n")
+
+		downIndexScript.WriteString(fmt.Sprintf(SECURE_DROP_INDEX, index.Name))
+		downIndexScript.WriteString("\n")
+	}
+
+	downScript.WriteString(fmt.Sprintf(SECURE_DROP_TABLE, qualified))
+	downScript.WriteString("\n")
+
+	// sequences only used by a default may be shared with other tables, so they are kept
+	for _, sequence := range sequences {
+		if !sequence.Owned {
+			continue
+		}
+
+		downScript.WriteString(fmt.Sprintf(SECURE_DROP_SEQUENCE, sequence.Name))
+		downScript.WriteString("\n")
+	}
+
+	ddl.index.UpScript = ddlReplacer.Replace(upIndexScript.String())
+	ddl.index.DownScript = downIndexScript.String()
+	ddl.Definition.UpScript = ddlReplacer.Replace(upScript.String()) + ddl.index.UpScript
+	ddl.Definition.DownScript = ddl.index.DownScript + downScript.String()
+	ddl.Reference.UpScript = upReferenceScript.String()
+	ddl.Reference.DownScript = downReferenceScript.String()
+	ddl.ForeignKey.UpScript = upForeignScript.String()
+	ddl.ForeignKey.DownScript = downForeignScript.String()
+
+	// rows of a partitioned table live in its partitions
+	if schemaOnly || partition.Key != "" {
+		return ddl, nil
+	}
+
+	insert, err := c.rows(schema, table, qualified, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	ddl.Insert = insert
+
+	return ddl, nil
+}
+
+func (catalog) createTable(qualified string, definitions []string, partition *catalogPartition) string {
+	var script strings.Builder
+
+	script.WriteString(fmt.Sprintf("%s %s", CREATE_TABLE, qualified))
+	if partition.Parent != "" {
+		script.WriteString(fmt.Sprintf(" PARTITION OF %s", partition.Parent))
+	}
+
+	if len(definitions) > 0 {
+		script.WriteString(fmt.Sprintf(" (\n    %s\n)", strings.Join(definitions, ",\n    ")))
+	}
+
+	if partition.Parent != "" {
+		script.WriteString(fmt.Sprintf("\n    %s", partition.Bound))
+	}
+
+	if partition.Key != "" {
+		script.WriteString(fmt.Sprintf("\n    PARTITION BY %s", partition.Key))
+	}
+
+	script.WriteString(";\n")
+
+	return script.String()
+}
+
+func (catalog) columnDefinition(column *catalogColumn) string {
+	var definition strings.Builder
+
+	definition.WriteString(column.Name)
+	definition.WriteString(" ")
+	definition.WriteString(column.DataType)
+	definition.WriteString(column.Collation)
+
+	switch {
+	case column.Generated == "s":
+		definition.WriteString(fmt.Sprintf(" GENERATED ALWAYS AS (%s) STORED", column.DefaultValue))
+	case column.Generated == "v":
+		definition.WriteString(fmt.Sprintf(" GENERATED ALWAYS AS (%s)", column.DefaultValue))
+	case column.Identity == "a":
+		definition.WriteString(" GENERATED ALWAYS AS IDENTITY")
+	case column.Identity == "d":
+		definition.WriteString(" GENERATED BY DEFAULT AS IDENTITY")
+	case column.DefaultValue != "":
+		definition.WriteString(" DEFAULT ")
+		definition.WriteString(column.DefaultValue)
+	}
+
+	if column.NotNull {
+		definition.WriteString(" NOT NULL")
+	}
+
+	return definition.String()
+}
+
+func (c *catalog) columns(schema string, table string) ([]*catalogColumn, error) {
+	rows, err := c.db.Query(fmt.Sprintf(QUERY_CATALOG_COLUMN, schema, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []*catalogColumn{}
+	for rows.Next() {
+		column := catalogColumn{}
+		err := rows.Scan(
+			&column.Name,
+			&column.DataType,
+			&column.Collation,
+			&column.DefaultValue,
+			&column.Identity,
+			&column.Generated,
+			&column.NotNull,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, &column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s.%s not found", schema, table)
+	}
+
+	return columns, nil
+}
+
+func (c *catalog) sequences(schema string, table string) ([]*catalogSequence, error) {
+	rows, err := c.db.Query(fmt.Sprintf(QUERY_CATALOG_SEQUENCE, schema, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sequences := []*catalogSequence{}
+	for rows.Next() {
+		sequence := catalogSequence{}
+		err := rows.Scan(
+			&sequence.Name,
+			&sequence.Column,
+			&sequence.DataType,
+			&sequence.StartValue,
+			&sequence.IncrementBy,
+			&sequence.MinValue,
+			&sequence.MaxValue,
+			&sequence.CacheSize,
+			&sequence.Cycle,
+			&sequence.Owned,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sequences = append(sequences, &sequence)
+	}
+
+	return sequences, rows.Err()
+}
+
+func (c *catalog) partition(schema string, table string) (*catalogPartition, error) {
+	partition := catalogPartition{}
+	err := c.db.QueryRow(fmt.Sprintf(QUERY_CATALOG_PARTITION, schema, table)).Scan(&partition.Key, &partition.Parent, &partition.Bound)
+
+	return &partition, err
+}
+
+func (c *catalog) objects(query string, schema string, table string) ([]*catalogObject, error) {
+	rows, err := c.db.Query(fmt.Sprintf(query, schema, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []*catalogObject{}
+	for rows.Next() {
+		object := catalogObject{}
+		if len(columns) == 3 {
+			err = rows.Scan(&object.Name, &object.Type, &object.Definition)
+		} else {
+			err = rows.Scan(&object.Name, &object.Definition)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, &object)
+	}
+
+	return objects, rows.Err()
+}
+
+func (c *catalog) primaryKey(schema string, table string) ([]string, error) {
+	rows, err := c.db.Query(fmt.Sprintf(QUERY_CATALOG_PRIMARY_KEY, schema, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (c *catalog) rows(schema string, table string, qualified string, columns []*catalogColumn) (*Migration, error) {
+	keys, err := c.primaryKey(schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var overriding string
+	names := make([]string, 0, len(columns))
+	values := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if column.Generated != "" {
+			continue
+		}
+
+		if column.Identity == "a" {
+			overriding = " OVERRIDING SYSTEM VALUE"
+		}
+
+		names = append(names, column.Name)
+		values = append(values, fmt.Sprintf("quote_nullable(%s::text)", column.Name))
+	}
+
+	rowKey := "''"
+	orderBy := "1"
+	if len(keys) > 0 {
+		conditions := make([]string, 0, len(keys))
+		for _, key := range keys {
+			conditions = append(conditions, fmt.Sprintf("'%s = ' || quote_nullable(%s::text)", strings.ReplaceAll(key, "'", "''"), key))
+		}
+
+		rowKey = strings.Join(conditions, " || ' AND ' || ")
+		orderBy = strings.Join(keys, ", ")
+	}
+
+	rows, err := c.db.Query(fmt.Sprintf(QUERY_CATALOG_ROWS, strings.Join(values, " || ', ' || "), rowKey, qualified, orderBy))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var insertScript strings.Builder
+	var deleteScript strings.Builder
+
+	for rows.Next() {
+		var value, key string
+		if err := rows.Scan(&value, &key); err != nil {
+			return nil, err
+		}
+
+		insertScript.WriteString(fmt.Sprintf("%s %s (%s)%s VALUES (%s);\n", INSERT_INTO, qualified, strings.Join(names, ", "), overriding, value))
+		if key != "" {
+			deleteScript.WriteString(fmt.Sprintf("DELETE FROM %s WHERE %s;\n", qualified, key))
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Migration{
+		UpScript:   insertScript.String(),
+		DownScript: deleteScript.String(),
+	}, nil
+}
+
+func (c *catalog) qualifiedName(schema string, table string) (string, error) {
+	var name string
+
+	err := c.db.QueryRow(fmt.Sprintf(QUERY_CATALOG_QUALIFIED_NAME, schema, table)).Scan(&name)
+
+	return name, err
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	KIND_ENUM              = "enum"
+	KIND_TABLE             = "table"
+	KIND_FUNCTION          = "function"
+	KIND_VIEW              = "view"
+	KIND_MATERIALIZED_VIEW = "materialized_view"
+)
+
+type (
+	dependency struct {
+		db *sql.DB
+	}
+
+	Node struct {
+		Kind string
+		Name string
+	}
+
+	Graph struct {
+		nodes map[Node]struct{}
+		edges map[Node]map[Node]struct{}
+	}
+)
+
+var kindRank = map[string]int{
+	KIND_ENUM:              0,
+	KIND_TABLE:             1,
+	KIND_FUNCTION:          2,
+	KIND_VIEW:              3,
+	KIND_MATERIALIZED_VIEW: 4,
+}
+
+func NewDependency(db *sql.DB) *dependency {
+	return &dependency{db: db}
+}
+
+func (d *dependency) Objects(schema string) (*Graph, error) {
+	rows, err := d.db.Query(fmt.Sprintf(QUERY_DEPENDENCY, schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	graph := NewGraph()
+	for rows.Next() {
+		var node, reference Node
+		if err := rows.Scan(&node.Kind, &node.Name, &reference.Kind, &reference.Name); err != nil {
+			return nil, err
+		}
+
+		graph.AddEdge(node, reference)
+	}
+
+	return graph, rows.Err()
+}
+
+func (d *dependency) ForeignKeys(schema string) (*Graph, error) {
+	rows, err := d.db.Query(fmt.Sprintf(QUERY_FOREIGN_KEY_DEPENDENCY, schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	graph := NewGraph()
+	for rows.Next() {
+		var table, reference string
+		if err := rows.Scan(&table, &reference); err != nil {
+			return nil, err
+		}
+
+		graph.AddEdge(Node{Kind: KIND_TABLE, Name: table}, Node{Kind: KIND_TABLE, Name: reference})
+	}
+
+	return graph, rows.Err()
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		nodes: make(map[Node]struct{}),
+		edges: make(map[Node]map[Node]struct{}),
+	}
+}
+
+func (n Node) String() string {
+	return fmt.Sprintf("%s %s", strings.ReplaceAll(n.Kind, "_", " "), n.Name)
+}
+
+func (g *Graph) AddNode(node Node) {
+	g.nodes[node] = struct{}{}
+}
+
+// AddEdge records that node depends on reference, so reference must be created first.
+func (g *Graph) AddEdge(node Node, reference Node) {
+	g.AddNode(node)
+	g.AddNode(reference)
+
+	if node == reference {
+		return
+	}
+
+	if _, ok := g.edges[node]; !ok {
+		g.edges[node] = make(map[Node]struct{})
+	}
+
+	g.edges[node][reference] = struct{}{}
+}
+
+// Subgraph keeps the given nodes and everything they depend on, directly or not.
+func (g *Graph) Subgraph(nodes ...Node) *Graph {
+	subgraph := NewGraph()
+	queue := slices.Clone(nodes)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if _, ok := subgraph.nodes[node]; ok {
+			continue
+		}
+
+		subgraph.AddNode(node)
+		for reference := range g.edges[node] {
+			if _, ok := subgraph.edges[node]; !ok {
+				subgraph.edges[node] = make(map[Node]struct{})
+			}
+
+			subgraph.edges[node][reference] = struct{}{}
+			queue = append(queue, reference)
+		}
+	}
+
+	return subgraph
+}
+
+// Sort returns every node after the nodes it depends on, unrelated nodes keep the kind order then name.
+func (g *Graph) Sort() ([]Node, error) {
+	pending := make(map[Node]int, len(g.nodes))
+	dependents := make(map[Node][]Node, len(g.nodes))
+	for node := range g.nodes {
+		pending[node] = len(g.edges[node])
+		for reference := range g.edges[node] {
+			dependents[reference] = append(dependents[reference], node)
+		}
+	}
+
+	ready := []Node{}
+	for node, total := range pending {
+		if total == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	sorted := make([]Node, 0, len(g.nodes))
+	for len(ready) > 0 {
+		slices.SortFunc(ready, compareNode)
+
+		node := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, node)
+
+		for _, dependent := range dependents[node] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(sorted) < len(g.nodes) {
+		return sorted, fmt.Errorf("dependency cycle detected: %s", g.cycle(pending))
+	}
+
+	return sorted, nil
+}
+
+func (g *Graph) cycle(pending map[Node]int) string {
+	remaining := []Node{}
+	for node, total := range pending {
+		if total > 0 {
+			remaining = append(remaining, node)
+		}
+	}
+
+	slices.SortFunc(remaining, compareNode)
+
+	visited := make(map[Node]int)
+	path := []Node{remaining[0]}
+	for {
+		node := path[len(path)-1]
+		if index, ok := visited[node]; ok {
+			path = path[index:]
+
+			break
+		}
+
+		visited[node] = len(path) - 1
+
+		next := []Node{}
+		for reference := range g.edges[node] {
+			if pending[reference] > 0 {
+				next = append(next, reference)
+			}
+		}
+
+		slices.SortFunc(next, compareNode)
+		path = append(path, next[0])
+	}
+
+	names := make([]string, 0, len(path))
+	for _, node := range path {
+		names = append(names, node.String())
+	}
+
+	return strings.Join(names, " -> ")
+}
+
+func compareNode(a Node, b Node) int {
+	if kindRank[a.Kind] != kindRank[b.Kind] {
+		return kindRank[a.Kind] - kindRank[b.Kind]
+	}
+
+	return strings.Compare(a.Name, b.Name)
+}
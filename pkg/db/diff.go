@@ -0,0 +1,490 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	phaseDropView = iota
+	phaseCreateEnum
+	phaseCreateFunction
+	phaseCreateTable
+	phaseAlterColumn
+	phaseCreateSqlFunction
+	phaseDropForeignKey
+	phaseDropConstraint
+	phaseDropIndex
+	phaseDropColumn
+	phaseAddConstraint
+	phaseAddForeignKey
+	phaseCreateIndex
+	phaseCreateView
+	phaseDropTable
+	phaseDropFunction
+	phaseDropEnum
+)
+
+// reSqlLanguage matches functions whose body postgresql checks against existing tables on creation.
+var reSqlLanguage = regexp.MustCompile(`(?mi)^\s*LANGUAGE\s+sql\b`)
+
+type (
+	change struct {
+		UpScript   string
+		DownScript string
+		phase      int
+	}
+
+	changes []*change
+)
+
+// Diff returns the migration that turns target into source, the down script turns it back.
+func Diff(source *Snapshot, target *Snapshot) *Migration {
+	result := changes{}
+
+	result.enums(source, target)
+	result.functions(source, target)
+	result.tables(source, target)
+	result.views(source, target)
+
+	slices.SortStableFunc(result, func(a *change, b *change) int {
+		return a.phase - b.phase
+	})
+
+	upScripts := make([]string, 0, len(result))
+	downScripts := make([]string, 0, len(result))
+	for i := range result {
+		if result[i].UpScript != "" {
+			upScripts = append(upScripts, strings.TrimSpace(result[i].UpScript))
+		}
+
+		down := result[len(result)-1-i]
+		if down.DownScript != "" {
+			downScripts = append(downScripts, strings.TrimSpace(down.DownScript))
+		}
+	}
+
+	if len(upScripts) == 0 {
+		return &Migration{Name: source.schema}
+	}
+
+	return &Migration{
+		Name:       source.schema,
+		UpScript:   strings.Join(upScripts, "\n\n") + "\n",
+		DownScript: strings.Join(downScripts, "\n\n") + "\n",
+	}
+}
+
+func (c *changes) add(phase int, upScript string, downScript string) {
+	*c = append(*c, &change{phase: phase, UpScript: upScript, DownScript: downScript})
+}
+
+func (c *changes) enums(source *Snapshot, target *Snapshot) {
+	tool := enum{}
+	for _, name := range sortedKeys(source.enums) {
+		sEnum := source.enums[name]
+		tEnum, ok := target.enums[name]
+		if !ok {
+			c.add(phaseCreateEnum, tool.createDdl(sEnum.Name, sEnum.Definition), fmt.Sprintf(SECURE_DROP_TYPE, sEnum.Name))
+
+			continue
+		}
+
+		tValues := enumValues(tEnum.Definition)
+		sValues := enumValues(sEnum.Definition)
+		for i, value := range sValues {
+			if slices.Contains(tValues, value) {
+				continue
+			}
+
+			c.add(
+				phaseCreateEnum,
+				fmt.Sprintf(SQL_ADD_ENUM_VALUE, sEnum.Name, quoteValue(value), enumPosition(sValues, tValues, i)),
+				fmt.Sprintf("-- Value '%s' can not be removed from %s", value, sEnum.Name),
+			)
+		}
+	}
+
+	for _, name := range sortedKeys(target.enums) {
+		if _, ok := source.enums[name]; ok {
+			continue
+		}
+
+		tEnum := target.enums[name]
+		c.add(phaseDropEnum, fmt.Sprintf(SECURE_DROP_TYPE, tEnum.Name), tool.createDdl(tEnum.Name, tEnum.Definition))
+	}
+}
+
+// enumPosition places a new value after the value before it in source, which exists by then, or before the first value target already has.
+func enumPosition(sValues []string, tValues []string, index int) string {
+	if index > 0 {
+		return fmt.Sprintf(" AFTER '%s'", quoteValue(sValues[index-1]))
+	}
+
+	for _, value := range sValues[1:] {
+		if slices.Contains(tValues, value) {
+			return fmt.Sprintf(" BEFORE '%s'", quoteValue(value))
+		}
+	}
+
+	return ""
+}
+
+func quoteValue(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
+func (c *changes) functions(source *Snapshot, target *Snapshot) {
+	for _, name := range sortedKeys(source.functions) {
+		sFunction := source.functions[name]
+		phase := phaseCreateFunction
+		if reSqlLanguage.MatchString(sFunction.Definition) {
+			phase = phaseCreateSqlFunction
+		}
+
+		tFunction, ok := target.functions[name]
+		if !ok {
+			c.add(phase, sFunction.Definition+";", fmt.Sprintf(SECURE_DROP_ROUTINE, sFunction.Name, sFunction.Arguments))
+
+			continue
+		}
+
+		if sFunction.Definition != tFunction.Definition {
+			c.add(phase, sFunction.Definition+";", tFunction.Definition+";")
+		}
+	}
+
+	for _, name := range sortedKeys(target.functions) {
+		if _, ok := source.functions[name]; ok {
+			continue
+		}
+
+		tFunction := target.functions[name]
+		c.add(phaseDropFunction, fmt.Sprintf(SECURE_DROP_ROUTINE, tFunction.Name, tFunction.Arguments), tFunction.Definition+";")
+	}
+}
+
+// views drops views in reverse dependency order and creates them in dependency order. A view whose columns can't be
+// replaced, a changed materialized view and every view depending on them are dropped and created again.
+func (c *changes) views(source *Snapshot, target *Snapshot) {
+	recreate := map[Node]bool{}
+	for _, node := range target.viewOrder() {
+		tView := target.view(node)
+		sView := source.view(node)
+		if sView == nil {
+			continue
+		}
+
+		replaceable := node.Kind == KIND_VIEW && (sView.Columns == tView.Columns || strings.HasPrefix(sView.Columns, tView.Columns+", "))
+		if sView.Definition != tView.Definition && !replaceable {
+			recreate[node] = true
+		}
+
+		for reference := range target.graph.edges[node] {
+			if recreate[reference] {
+				recreate[node] = true
+			}
+		}
+	}
+
+	order := target.viewOrder()
+	for i := len(order) - 1; i >= 0; i-- {
+		node := order[i]
+		if source.view(node) == nil || recreate[node] {
+			tView := target.view(node)
+			c.add(phaseDropView, dropView(node, tView), createView(node, tView))
+		}
+	}
+
+	for _, node := range source.viewOrder() {
+		sView := source.view(node)
+		tView := target.view(node)
+		switch {
+		case tView == nil || recreate[node]:
+			c.add(phaseCreateView, createView(node, sView), dropView(node, sView))
+		case sView.Definition != tView.Definition:
+			c.add(phaseCreateView, createView(node, sView), createView(node, tView))
+		case node.Kind == KIND_MATERIALIZED_VIEW:
+			c.indexes(sView.Indexes, tView.Indexes)
+		}
+	}
+}
+
+func createView(node Node, view *snapshotObject) string {
+	if node.Kind == KIND_VIEW {
+		return fmt.Sprintf(SECURE_CREATE_VIEW, view.Name, view.Definition)
+	}
+
+	scripts := []string{fmt.Sprintf(SECURE_CREATE_MATERIALIZED_VIEW, view.Name, view.Definition)}
+	for _, name := range sortedKeys(view.Indexes) {
+		scripts = append(scripts, view.Indexes[name].Definition+";")
+	}
+
+	return strings.Join(scripts, "\n")
+}
+
+func dropView(node Node, view *snapshotObject) string {
+	if node.Kind == KIND_VIEW {
+		return fmt.Sprintf(SECURE_DROP_VIEW, view.Name)
+	}
+
+	return fmt.Sprintf(SECURE_DROP_MATERIALIZED_VIEW, view.Name)
+}
+
+func (c *changes) tables(source *Snapshot, target *Snapshot) {
+	for _, name := range sortedKeys(source.tables) {
+		sTable := source.tables[name]
+		tTable, ok := target.tables[name]
+		if !ok {
+			c.add(
+				phaseCreateTable,
+				sTable.Ddl.Definition.UpScript+"\n"+sTable.Ddl.Reference.UpScript,
+				sTable.Ddl.Reference.DownScript+"\n"+sTable.Ddl.Definition.DownScript,
+			)
+			c.add(phaseAddForeignKey, sTable.Ddl.ForeignKey.UpScript, sTable.Ddl.ForeignKey.DownScript)
+
+			continue
+		}
+
+		c.columns(sTable, tTable)
+		c.constraints(sTable, tTable)
+		c.indexes(sTable.Indexes, tTable.Indexes)
+	}
+
+	for _, name := range sortedKeys(target.tables) {
+		if _, ok := source.tables[name]; ok {
+			continue
+		}
+
+		tTable := target.tables[name]
+		c.add(phaseDropForeignKey, tTable.Ddl.ForeignKey.DownScript, tTable.Ddl.ForeignKey.UpScript)
+		c.add(
+			phaseDropTable,
+			tTable.Ddl.Reference.DownScript+"\n"+tTable.Ddl.Definition.DownScript,
+			tTable.Ddl.Definition.UpScript+"\n"+tTable.Ddl.Reference.UpScript,
+		)
+	}
+}
+
+func (c *changes) columns(source *snapshotTable, target *snapshotTable) {
+	tool := catalog{}
+	for _, sColumn := range source.Columns {
+		tColumn := target.column(sColumn.Name)
+		if tColumn == nil {
+			c.add(phaseAlterColumn, c.addColumn(tool, source, sColumn), c.dropColumn(source, sColumn))
+
+			continue
+		}
+
+		if sColumn.Generated != "" && (sColumn.Generated != tColumn.Generated || sColumn.DefaultValue != tColumn.DefaultValue) ||
+			tColumn.Generated != "" && tColumn.Generated != "s" && sColumn.Generated == "" {
+			// a generation expression can't be added or changed in place, so the column is recreated
+			c.add(
+				phaseAlterColumn,
+				c.dropColumn(target, tColumn)+"\n"+c.addColumn(tool, source, sColumn),
+				c.dropColumn(source, sColumn)+"\n"+c.addColumn(tool, target, tColumn),
+			)
+
+			continue
+		}
+
+		if sColumn.DataType != tColumn.DataType || sColumn.Collation != tColumn.Collation {
+			c.add(
+				phaseAlterColumn,
+				fmt.Sprintf(SQL_ALTER_COLUMN, source.Name, sColumn.Name, fmt.Sprintf("TYPE %s%s", sColumn.DataType, sColumn.Collation)),
+				fmt.Sprintf(SQL_ALTER_COLUMN, target.Name, tColumn.Name, fmt.Sprintf("TYPE %s%s", tColumn.DataType, tColumn.Collation)),
+			)
+		}
+
+		tDefault := tColumn.DefaultValue
+		if tColumn.Generated != "" && sColumn.Generated == "" {
+			c.add(
+				phaseAlterColumn,
+				fmt.Sprintf(SQL_ALTER_COLUMN, source.Name, sColumn.Name, "DROP EXPRESSION IF EXISTS"),
+				c.dropColumn(target, tColumn)+"\n"+c.addColumn(tool, target, tColumn),
+			)
+
+			tDefault = ""
+		}
+
+		// an identity is added after the column is not null without default, and dropped before they change back
+		if tColumn.Identity != "" || sColumn.Identity == "" {
+			c.identity(source, sColumn, tColumn)
+		}
+
+		if sColumn.NotNull != tColumn.NotNull {
+			c.add(
+				phaseAlterColumn,
+				fmt.Sprintf(SQL_ALTER_COLUMN, source.Name, sColumn.Name, nullable(sColumn.NotNull)),
+				fmt.Sprintf(SQL_ALTER_COLUMN, target.Name, tColumn.Name, nullable(tColumn.NotNull)),
+			)
+		}
+
+		if sColumn.DefaultValue != tDefault {
+			c.add(
+				phaseAlterColumn,
+				fmt.Sprintf(SQL_ALTER_COLUMN, source.Name, sColumn.Name, defaultValue(sColumn.DefaultValue)),
+				fmt.Sprintf(SQL_ALTER_COLUMN, target.Name, tColumn.Name, defaultValue(tDefault)),
+			)
+		}
+
+		if tColumn.Identity == "" && sColumn.Identity != "" {
+			c.identity(source, sColumn, tColumn)
+		}
+	}
+
+	for _, tColumn := range target.Columns {
+		if source.column(tColumn.Name) != nil {
+			continue
+		}
+
+		c.add(phaseDropColumn, c.dropColumn(target, tColumn), c.addColumn(tool, target, tColumn))
+	}
+}
+
+func (c *changes) identity(table *snapshotTable, source *catalogColumn, target *catalogColumn) {
+	switch {
+	case source.Identity == target.Identity:
+	case target.Identity == "":
+		c.add(
+			phaseAlterColumn,
+			fmt.Sprintf(SQL_ALTER_COLUMN, table.Name, source.Name, fmt.Sprintf("ADD GENERATED %s AS IDENTITY", identityKind(source.Identity))),
+			fmt.Sprintf(SQL_ALTER_COLUMN, table.Name, target.Name, "DROP IDENTITY IF EXISTS"),
+		)
+	case source.Identity == "":
+		c.add(
+			phaseAlterColumn,
+			fmt.Sprintf(SQL_ALTER_COLUMN, table.Name, source.Name, "DROP IDENTITY IF EXISTS"),
+			fmt.Sprintf(SQL_ALTER_COLUMN, table.Name, target.Name, fmt.Sprintf("ADD GENERATED %s AS IDENTITY", identityKind(target.Identity))),
+		)
+	default:
+		c.add(
+			phaseAlterColumn,
+			fmt.Sprintf(SQL_ALTER_COLUMN, table.Name, source.Name, fmt.Sprintf("SET GENERATED %s", identityKind(source.Identity))),
+			fmt.Sprintf(SQL_ALTER_COLUMN, table.Name, target.Name, fmt.Sprintf("SET GENERATED %s", identityKind(target.Identity))),
+		)
+	}
+}
+
+func (changes) addColumn(tool catalog, table *snapshotTable, column *catalogColumn) string {
+	var script strings.Builder
+
+	sequence, ok := table.Sequences[column.Name]
+	if ok {
+		var cycle string
+		if sequence.Cycle {
+			cycle = "\n    CYCLE"
+		}
+
+		script.WriteString(fmt.Sprintf(
+			SQL_CREATE_SEQUENCE,
+			sequence.Name,
+			sequence.DataType,
+			sequence.StartValue,
+			sequence.IncrementBy,
+			sequence.MinValue,
+			sequence.MaxValue,
+			sequence.CacheSize,
+			cycle,
+		))
+		script.WriteString("\n")
+	}
+
+	script.WriteString(fmt.Sprintf(SECURE_ADD_COLUMN, table.Name, tool.columnDefinition(column)))
+
+	if ok && sequence.Owned {
+		script.WriteString("\n")
+		script.WriteString(fmt.Sprintf(SQL_SEQUENCE_OWNED_BY, sequence.Name, table.Name, sequence.Column))
+	}
+
+	return script.String()
+}
+
+func (changes) dropColumn(table *snapshotTable, column *catalogColumn) string {
+	return fmt.Sprintf(SECURE_DROP_COLUMN, table.Name, column.Name)
+}
+
+func (c *changes) constraints(source *snapshotTable, target *snapshotTable) {
+	for _, name := range sortedKeys(source.Constraints) {
+		sConstraint := source.Constraints[name]
+		tConstraint, ok := target.Constraints[name]
+		if ok && sConstraint.Definition == tConstraint.Definition {
+			continue
+		}
+
+		dropPhase, addPhase := phaseDropConstraint, phaseAddConstraint
+		if sConstraint.Type == "f" {
+			dropPhase, addPhase = phaseDropForeignKey, phaseAddForeignKey
+		}
+
+		if ok {
+			c.add(dropPhase, fmt.Sprintf(SECURE_DROP_CONSTRAINT, target.Name, name), fmt.Sprintf(SQL_ADD_CONSTRAINT, target.Name, name, tConstraint.Definition))
+		}
+
+		c.add(addPhase, fmt.Sprintf(SQL_ADD_CONSTRAINT, source.Name, name, sConstraint.Definition), fmt.Sprintf(SECURE_DROP_CONSTRAINT, source.Name, name))
+	}
+
+	for _, name := range sortedKeys(target.Constraints) {
+		if _, ok := source.Constraints[name]; ok {
+			continue
+		}
+
+		tConstraint := target.Constraints[name]
+		dropPhase := phaseDropConstraint
+		if tConstraint.Type == "f" {
+			dropPhase = phaseDropForeignKey
+		}
+
+		c.add(dropPhase, fmt.Sprintf(SECURE_DROP_CONSTRAINT, target.Name, name), fmt.Sprintf(SQL_ADD_CONSTRAINT, target.Name, name, tConstraint.Definition))
+	}
+}
+
+func (c *changes) indexes(source map[string]*catalogObject, target map[string]*catalogObject) {
+	for _, name := range sortedKeys(source) {
+		sIndex := source[name]
+		tIndex, ok := target[name]
+		if ok && sIndex.Definition == tIndex.Definition {
+			continue
+		}
+
+		if ok {
+			c.add(phaseDropIndex, fmt.Sprintf(SECURE_DROP_INDEX, name), tIndex.Definition+";")
+		}
+
+		c.add(phaseCreateIndex, sIndex.Definition+";", fmt.Sprintf(SECURE_DROP_INDEX, name))
+	}
+
+	for _, name := range sortedKeys(target) {
+		if _, ok := source[name]; ok {
+			continue
+		}
+
+		c.add(phaseDropIndex, fmt.Sprintf(SECURE_DROP_INDEX, name), target[name].Definition+";")
+	}
+}
+
+func nullable(notNull bool) string {
+	if notNull {
+		return "SET NOT NULL"
+	}
+
+	return "DROP NOT NULL"
+}
+
+func identityKind(identity string) string {
+	if identity == "a" {
+		return "ALWAYS"
+	}
+
+	return "BY DEFAULT"
+}
+
+func defaultValue(value string) string {
+	if value == "" {
+		return "DROP DEFAULT"
+	}
+
+	return fmt.Sprintf("SET DEFAULT %s", value)
+}
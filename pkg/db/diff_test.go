@@ -0,0 +1,60 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func viewSnapshot(views map[string]*snapshotObject, edges map[string]string) *Snapshot {
+	snapshot := EmptySnapshot("public")
+	snapshot.views = views
+	for node, reference := range edges {
+		snapshot.graph.AddEdge(Node{Kind: KIND_VIEW, Name: node}, Node{Kind: KIND_VIEW, Name: reference})
+	}
+
+	return snapshot
+}
+
+func TestDiffCreatesViewsInDependencyOrder(t *testing.T) {
+	source := viewSnapshot(map[string]*snapshotObject{
+		"a_report": {Name: "public.a_report", Definition: "SELECT id FROM public.z_base;", Columns: "id integer"},
+		"z_base":   {Name: "public.z_base", Definition: "SELECT 1 AS id;", Columns: "id integer"},
+	}, map[string]string{"a_report": "z_base"})
+
+	migration := Diff(source, EmptySnapshot("public"))
+	if strings.Index(migration.UpScript, "public.z_base") > strings.Index(migration.UpScript, "public.a_report AS") {
+		t.Errorf("z_base must be created before a_report:\n%s", migration.UpScript)
+	}
+}
+
+func TestDiffRecreatesViewWithDroppedColumn(t *testing.T) {
+	target := viewSnapshot(map[string]*snapshotObject{
+		"orders":  {Name: "public.orders", Definition: "SELECT 1 AS id, 2 AS total;", Columns: "id integer, total integer"},
+		"summary": {Name: "public.summary", Definition: "SELECT id FROM public.orders;", Columns: "id integer"},
+	}, map[string]string{"summary": "orders"})
+	source := viewSnapshot(map[string]*snapshotObject{
+		"orders":  {Name: "public.orders", Definition: "SELECT 1 AS id;", Columns: "id integer"},
+		"summary": {Name: "public.summary", Definition: "SELECT id FROM public.orders;", Columns: "id integer"},
+	}, map[string]string{"summary": "orders"})
+
+	want := strings.Join([]string{
+		"DROP VIEW IF EXISTS public.summary;",
+		"DROP VIEW IF EXISTS public.orders;",
+		"CREATE OR REPLACE VIEW public.orders AS SELECT 1 AS id;",
+		"CREATE OR REPLACE VIEW public.summary AS SELECT id FROM public.orders;",
+	}, "\n\n") + "\n"
+
+	if migration := Diff(source, target); migration.UpScript != want {
+		t.Errorf("UpScript =\n%s\nwant\n%s", migration.UpScript, want)
+	}
+}
+
+func TestDiffReplacesViewWithAddedColumn(t *testing.T) {
+	target := viewSnapshot(map[string]*snapshotObject{"orders": {Name: "public.orders", Definition: "SELECT 1 AS id;", Columns: "id integer"}}, nil)
+	source := viewSnapshot(map[string]*snapshotObject{"orders": {Name: "public.orders", Definition: "SELECT 1 AS id, 2 AS total;", Columns: "id integer, total integer"}}, nil)
+
+	want := "CREATE OR REPLACE VIEW public.orders AS SELECT 1 AS id, 2 AS total;\n"
+	if migration := Diff(source, target); migration.UpScript != want {
+		t.Errorf("UpScript = %q, want %q", migration.UpScript, want)
+	}
+}
@@ -33,6 +33,10 @@ type (
 	Migrate interface {
 		GenerateDdl(schema string) []*Migration
 	}
+
+	Generator interface {
+		Generate(name string, schemaOnly bool) (*Ddl, error)
+	}
 )
 
 const (
@@ -64,6 +68,14 @@ const (
 
 	SECURE_CREATE_UNIQUE_INDEX = "CREATE UNIQUE INDEX IF NOT EXISTS"
 
+	CONCURRENT_CREATE_INDEX = "CREATE INDEX CONCURRENTLY IF NOT EXISTS"
+
+	CONCURRENT_CREATE_UNIQUE_INDEX = "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS"
+
+	DROP_INDEX = "DROP INDEX IF EXISTS"
+
+	CONCURRENT_DROP_INDEX = "DROP INDEX CONCURRENTLY IF EXISTS"
+
 	SECURE_CREATE_VIEW = "CREATE OR REPLACE VIEW %s AS %s"
 
 	SECURE_CREATE_MATERIALIZED_VIEW = "CREATE MATERIALIZED VIEW IF NOT EXISTS %s AS %s"
@@ -74,6 +86,32 @@ const (
 
 	SECURE_DROP_FUNCTION = "DROP FUNCTION IF EXISTS %s(%s);"
 
+	SECURE_DROP_ROUTINE = "DROP ROUTINE IF EXISTS %s(%s);"
+
+	SECURE_DROP_TABLE = "DROP TABLE IF EXISTS %s;"
+
+	SECURE_DROP_MATERIALIZED_VIEW = "DROP MATERIALIZED VIEW IF EXISTS %s;"
+
+	SECURE_ADD_COLUMN = "ALTER TABLE ONLY %s ADD COLUMN IF NOT EXISTS %s;"
+
+	SECURE_DROP_COLUMN = "ALTER TABLE ONLY %s DROP COLUMN IF EXISTS %s;"
+
+	SQL_ALTER_COLUMN = "ALTER TABLE ONLY %s ALTER COLUMN %s %s;"
+
+	SQL_ADD_ENUM_VALUE = "ALTER TYPE %s ADD VALUE IF NOT EXISTS '%s'%s;"
+
+	SECURE_DROP_SEQUENCE = "DROP SEQUENCE IF EXISTS %s;"
+
+	SECURE_DROP_INDEX = "DROP INDEX IF EXISTS %s;"
+
+	SECURE_DROP_CONSTRAINT = "ALTER TABLE IF EXISTS ONLY %s DROP CONSTRAINT IF EXISTS %s;"
+
+	SQL_ADD_CONSTRAINT = "ALTER TABLE ONLY %s\n    ADD CONSTRAINT %s %s;"
+
+	SQL_CREATE_SEQUENCE = "CREATE SEQUENCE IF NOT EXISTS %s\n    AS %s\n    START WITH %d\n    INCREMENT BY %d\n    MINVALUE %d\n    MAXVALUE %d\n    CACHE %d%s;"
+
+	SQL_SEQUENCE_OWNED_BY = "ALTER SEQUENCE %s OWNED BY %s.%s;"
+
 	SQL_CREATE_ENUM_OPEN = `
 DO $$ BEGIN
     CREATE TYPE %s AS ENUM (`
@@ -232,6 +270,356 @@ SELECT
 FROM information_schema.columns
 WHERE table_name = '%s'
 ORDER BY ordinal_position;`
+
+	QUERY_DEPENDENCY = `
+WITH objects AS (
+    SELECT
+        'pg_catalog.pg_class'::regclass::oid AS classid,
+        c.oid AS objid,
+        c.relname::text AS name,
+        CASE c.relkind
+            WHEN 'v' THEN 'view'
+            WHEN 'm' THEN 'materialized_view'
+            ELSE 'table'
+        END AS kind
+    FROM pg_catalog.pg_class c
+    JOIN pg_catalog.pg_namespace n
+        ON n.oid = c.relnamespace
+    WHERE n.nspname = '%[1]s'
+        AND c.relkind IN ('r', 'p', 'v', 'm')
+    UNION ALL
+    SELECT
+        'pg_catalog.pg_type'::regclass::oid AS classid,
+        t.oid AS objid,
+        t.typname::text AS name,
+        'enum' AS kind
+    FROM pg_catalog.pg_type t
+    JOIN pg_catalog.pg_namespace n
+        ON n.oid = t.typnamespace
+    WHERE n.nspname = '%[1]s'
+        AND t.typtype = 'e'
+    UNION ALL
+    SELECT
+        'pg_catalog.pg_proc'::regclass::oid AS classid,
+        p.oid AS objid,
+        p.proname::text AS name,
+        'function' AS kind
+    FROM pg_catalog.pg_proc p
+    JOIN pg_catalog.pg_namespace n
+        ON n.oid = p.pronamespace
+    WHERE n.nspname = '%[1]s'
+),
+dependencies AS (
+    SELECT
+        CASE
+            WHEN r.oid IS NOT NULL OR ad.oid IS NOT NULL OR co.oid IS NOT NULL THEN 'pg_catalog.pg_class'::regclass::oid
+            ELSE d.classid
+        END AS classid,
+        COALESCE(r.ev_class, ad.adrelid, co.conrelid, d.objid) AS objid,
+        CASE
+            WHEN t.typrelid <> 0 THEN 'pg_catalog.pg_class'::regclass::oid
+            ELSE d.refclassid
+        END AS refclassid,
+        CASE
+            WHEN t.typrelid <> 0 THEN t.typrelid
+            WHEN t.typcategory = 'A' AND t.typelem <> 0 THEN t.typelem
+            ELSE d.refobjid
+        END AS refobjid
+    FROM pg_catalog.pg_depend d
+    LEFT JOIN pg_catalog.pg_rewrite r
+        ON d.classid = 'pg_catalog.pg_rewrite'::regclass
+        AND r.oid = d.objid
+    LEFT JOIN pg_catalog.pg_attrdef ad
+        ON d.classid = 'pg_catalog.pg_attrdef'::regclass
+        AND ad.oid = d.objid
+    LEFT JOIN pg_catalog.pg_constraint co
+        ON d.classid = 'pg_catalog.pg_constraint'::regclass
+        AND co.oid = d.objid
+    LEFT JOIN pg_catalog.pg_type t
+        ON d.refclassid = 'pg_catalog.pg_type'::regclass
+        AND t.oid = d.refobjid
+    WHERE d.deptype = 'n'
+        AND (d.classid <> 'pg_catalog.pg_constraint'::regclass OR co.contype <> 'f')
+    UNION ALL
+    SELECT
+        'pg_catalog.pg_class'::regclass::oid AS classid,
+        i.inhrelid AS objid,
+        'pg_catalog.pg_class'::regclass::oid AS refclassid,
+        i.inhparent AS refobjid
+    FROM pg_catalog.pg_inherits i
+)
+SELECT DISTINCT
+    o.kind AS kind,
+    o.name AS name,
+    ro.kind AS reference_kind,
+    ro.name AS reference_name
+FROM dependencies x
+JOIN objects o
+    ON o.classid = x.classid
+    AND o.objid = x.objid
+JOIN objects ro
+    ON ro.classid = x.refclassid
+    AND ro.objid = x.refobjid
+WHERE o.classid <> ro.classid
+    OR o.objid <> ro.objid
+ORDER BY kind, name, reference_kind, reference_name;`
+
+	QUERY_FOREIGN_KEY_DEPENDENCY = `
+SELECT DISTINCT
+    c.relname::text AS table_name,
+    rc.relname::text AS reference_name
+FROM pg_catalog.pg_constraint co
+JOIN pg_catalog.pg_class c
+    ON c.oid = co.conrelid
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = c.relnamespace
+JOIN pg_catalog.pg_class rc
+    ON rc.oid = co.confrelid
+JOIN pg_catalog.pg_namespace rn
+    ON rn.oid = rc.relnamespace
+WHERE co.contype = 'f'
+    AND co.conrelid <> co.confrelid
+    AND n.nspname = '%[1]s'
+    AND rn.nspname = '%[1]s'
+ORDER BY table_name, reference_name;`
+
+	QUERY_SNAPSHOT_TABLE = `
+SELECT
+    c.relname::text AS name
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = c.relnamespace
+WHERE n.nspname = '%s'
+    AND c.relkind IN ('r', 'p')
+ORDER BY name;`
+
+	QUERY_SNAPSHOT_ENUM = `
+SELECT
+    t.typname::text AS name,
+    quote_ident(n.nspname) || '.' || quote_ident(t.typname) AS qualified_name,
+    pg_catalog.array_to_string(
+        ARRAY(
+            SELECT e.enumlabel
+            FROM pg_catalog.pg_enum e
+            WHERE e.enumtypid = t.oid
+            ORDER BY e.enumsortorder
+        ), '#'
+    ) AS values
+FROM pg_catalog.pg_type t
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = t.typnamespace
+WHERE n.nspname = '%s'
+    AND t.typtype = 'e'
+ORDER BY name;`
+
+	QUERY_SNAPSHOT_FUNCTION = `
+SELECT
+    p.proname::text AS name,
+    quote_ident(n.nspname) || '.' || quote_ident(p.proname) AS qualified_name,
+    pg_catalog.pg_get_function_identity_arguments(p.oid) AS arguments,
+    pg_catalog.pg_get_functiondef(p.oid) AS definition
+FROM pg_catalog.pg_proc p
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = p.pronamespace
+WHERE n.nspname = '%s'
+    AND p.prokind IN ('f', 'p')
+    AND NOT EXISTS (
+        SELECT 1
+        FROM pg_catalog.pg_depend d
+        WHERE d.classid = 'pg_catalog.pg_proc'::regclass
+            AND d.objid = p.oid
+            AND d.deptype = 'e'
+    )
+ORDER BY name, arguments;`
+
+	QUERY_SNAPSHOT_VIEW = `
+SELECT
+    c.relname::text AS name,
+    quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS qualified_name,
+    c.relkind::text AS kind,
+    pg_catalog.pg_get_viewdef(c.oid, true) AS definition,
+    COALESCE((
+        SELECT string_agg(quote_ident(a.attname) || ' ' || pg_catalog.format_type(a.atttypid, a.atttypmod), ', ' ORDER BY a.attnum)
+        FROM pg_catalog.pg_attribute a
+        WHERE a.attrelid = c.oid
+            AND a.attnum > 0
+            AND NOT a.attisdropped
+    ), '') AS columns
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = c.relnamespace
+WHERE n.nspname = '%s'
+    AND c.relkind IN ('v', 'm')
+ORDER BY name;`
+
+	QUERY_CATALOG_QUALIFIED_NAME = `
+SELECT
+    quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS name
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = c.relnamespace
+WHERE n.nspname = '%s'
+    AND c.relname = '%s';`
+
+	QUERY_CATALOG_COLUMN = `
+SELECT
+    quote_ident(a.attname) AS name,
+    pg_catalog.format_type(a.atttypid, a.atttypmod) AS data_type,
+    COALESCE((
+        SELECT ' COLLATE ' || quote_ident(cn.nspname) || '.' || quote_ident(co.collname)
+        FROM pg_catalog.pg_collation co
+        JOIN pg_catalog.pg_namespace cn
+            ON cn.oid = co.collnamespace
+        JOIN pg_catalog.pg_type ty
+            ON ty.oid = a.atttypid
+        WHERE co.oid = a.attcollation
+            AND a.attcollation <> ty.typcollation
+    ), '') AS collation,
+    COALESCE(pg_catalog.pg_get_expr(d.adbin, d.adrelid), '') AS default_value,
+    a.attidentity::text AS identity,
+    a.attgenerated::text AS generated,
+    a.attnotnull AS not_null
+FROM pg_catalog.pg_attribute a
+LEFT JOIN pg_catalog.pg_attrdef d
+    ON d.adrelid = a.attrelid
+    AND d.adnum = a.attnum
+WHERE a.attrelid = (quote_ident('%s') || '.' || quote_ident('%s'))::regclass
+    AND a.attnum > 0
+    AND NOT a.attisdropped
+ORDER BY a.attnum;`
+
+	QUERY_CATALOG_SEQUENCE = `
+WITH sequences AS (
+    SELECT DISTINCT ON (x.seqid)
+        x.seqid,
+        x.attnum,
+        x.owned
+    FROM (
+        SELECT
+            d.objid AS seqid,
+            d.refobjsubid AS attnum,
+            true AS owned
+        FROM pg_catalog.pg_depend d
+        WHERE d.classid = 'pg_catalog.pg_class'::regclass
+            AND d.refclassid = 'pg_catalog.pg_class'::regclass
+            AND d.refobjid = (quote_ident('%[1]s') || '.' || quote_ident('%[2]s'))::regclass
+            AND d.deptype = 'a'
+        UNION ALL
+        SELECT
+            d.refobjid AS seqid,
+            ad.adnum AS attnum,
+            false AS owned
+        FROM pg_catalog.pg_depend d
+        JOIN pg_catalog.pg_attrdef ad
+            ON ad.oid = d.objid
+        WHERE d.classid = 'pg_catalog.pg_attrdef'::regclass
+            AND d.refclassid = 'pg_catalog.pg_class'::regclass
+            AND ad.adrelid = (quote_ident('%[1]s') || '.' || quote_ident('%[2]s'))::regclass
+            AND d.deptype = 'n'
+    ) x
+    ORDER BY x.seqid, x.owned DESC
+)
+SELECT
+    quote_ident(n.nspname) || '.' || quote_ident(s.relname) AS name,
+    quote_ident(a.attname) AS column_name,
+    pg_catalog.format_type(q.seqtypid, NULL) AS data_type,
+    q.seqstart AS start_value,
+    q.seqincrement AS increment_by,
+    q.seqmin AS min_value,
+    q.seqmax AS max_value,
+    q.seqcache AS cache_size,
+    q.seqcycle AS cycle,
+    x.owned AS owned
+FROM sequences x
+JOIN pg_catalog.pg_class s
+    ON s.oid = x.seqid
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = s.relnamespace
+JOIN pg_catalog.pg_sequence q
+    ON q.seqrelid = s.oid
+JOIN pg_catalog.pg_attribute a
+    ON a.attrelid = (quote_ident('%[1]s') || '.' || quote_ident('%[2]s'))::regclass
+    AND a.attnum = x.attnum
+WHERE s.relkind = 'S'
+ORDER BY s.relname;`
+
+	QUERY_CATALOG_PARTITION = `
+SELECT
+    COALESCE(pg_catalog.pg_get_partkeydef(c.oid), '') AS partition_key,
+    COALESCE((
+        SELECT quote_ident(pn.nspname) || '.' || quote_ident(p.relname)
+        FROM pg_catalog.pg_inherits i
+        JOIN pg_catalog.pg_class p
+            ON p.oid = i.inhparent
+        JOIN pg_catalog.pg_namespace pn
+            ON pn.oid = p.relnamespace
+        WHERE i.inhrelid = c.oid
+            AND c.relispartition
+    ), '') AS parent,
+    COALESCE(pg_catalog.pg_get_expr(c.relpartbound, c.oid), '') AS bound
+FROM pg_catalog.pg_class c
+WHERE c.oid = (quote_ident('%s') || '.' || quote_ident('%s'))::regclass;`
+
+	QUERY_CATALOG_CONSTRAINT = `
+SELECT
+    quote_ident(c.conname) AS name,
+    c.contype::text AS type,
+    pg_catalog.pg_get_constraintdef(c.oid, true) AS definition
+FROM pg_catalog.pg_constraint c
+WHERE c.conrelid = (quote_ident('%s') || '.' || quote_ident('%s'))::regclass
+    AND c.contype IN ('p', 'u', 'x', 'c', 'f')
+    AND c.conislocal
+    AND c.conparentid = 0
+ORDER BY
+    CASE c.contype
+        WHEN 'p' THEN 0
+        WHEN 'u' THEN 1
+        WHEN 'x' THEN 2
+        WHEN 'c' THEN 3
+        ELSE 4
+    END,
+    c.conname;`
+
+	QUERY_CATALOG_INDEX = `
+SELECT
+    quote_ident(n.nspname) || '.' || quote_ident(ic.relname) AS name,
+    replace(pg_catalog.pg_get_indexdef(i.indexrelid), ' ON ONLY ', ' ON ') AS definition
+FROM pg_catalog.pg_index i
+JOIN pg_catalog.pg_class ic
+    ON ic.oid = i.indexrelid
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = ic.relnamespace
+WHERE i.indrelid = (quote_ident('%s') || '.' || quote_ident('%s'))::regclass
+    AND NOT EXISTS (
+        SELECT 1
+        FROM pg_catalog.pg_inherits h
+        WHERE h.inhrelid = i.indexrelid
+    )
+    AND NOT EXISTS (
+        SELECT 1
+        FROM pg_catalog.pg_constraint c
+        WHERE c.conindid = i.indexrelid
+            AND c.contype IN ('p', 'u', 'x')
+    )
+ORDER BY ic.relname;`
+
+	QUERY_CATALOG_PRIMARY_KEY = `
+SELECT
+    quote_ident(a.attname) AS name
+FROM pg_catalog.pg_index i
+JOIN pg_catalog.pg_attribute a
+    ON a.attrelid = i.indrelid
+    AND a.attnum = ANY(i.indkey)
+WHERE i.indrelid = (quote_ident('%s') || '.' || quote_ident('%s'))::regclass
+    AND i.indisprimary
+ORDER BY array_position(i.indkey::int2[], a.attnum);`
+
+	QUERY_CATALOG_ROWS = `
+SELECT
+    %s AS row_values,
+    %s AS row_key
+FROM %s
+ORDER BY %s;`
 )
 
 func streamMigration(db *sql.DB, query string, builder func(*sql.Rows) (*Migration, error)) <-chan *Migration {
@@ -0,0 +1,329 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type (
+	snapshot struct {
+		db *sql.DB
+	}
+
+	Snapshot struct {
+		schema            string
+		enums             map[string]*snapshotObject
+		functions         map[string]*snapshotObject
+		views             map[string]*snapshotObject
+		materializedViews map[string]*snapshotObject
+		tables            map[string]*snapshotTable
+		graph             *Graph
+	}
+
+	snapshotObject struct {
+		Name       string
+		Arguments  string
+		Definition string
+		Columns    string
+		Indexes    map[string]*catalogObject
+	}
+
+	snapshotTable struct {
+		Name        string
+		Ddl         *Ddl
+		Columns     []*catalogColumn
+		Sequences   map[string]*catalogSequence
+		Constraints map[string]*catalogObject
+		Indexes     map[string]*catalogObject
+	}
+)
+
+func NewSnapshot(db *sql.DB) *snapshot {
+	return &snapshot{db: db}
+}
+
+// EmptySnapshot is a schema without objects, a diff against it creates the whole schema.
+func EmptySnapshot(schema string) *Snapshot {
+	return &Snapshot{
+		schema:            schema,
+		enums:             make(map[string]*snapshotObject),
+		functions:         make(map[string]*snapshotObject),
+		views:             make(map[string]*snapshotObject),
+		materializedViews: make(map[string]*snapshotObject),
+		tables:            make(map[string]*snapshotTable),
+		graph:             NewGraph(),
+	}
+}
+
+func (s *snapshot) Take(schema string, excludes ...string) (*Snapshot, error) {
+	result := EmptySnapshot(schema)
+
+	if err := s.enums(result); err != nil {
+		return nil, err
+	}
+
+	if err := s.functions(result); err != nil {
+		return nil, err
+	}
+
+	if err := s.views(result); err != nil {
+		return nil, err
+	}
+
+	if err := s.tables(result, excludes...); err != nil {
+		return nil, err
+	}
+
+	graph, err := NewDependency(s.db).Objects(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	result.graph = graph
+
+	return result, nil
+}
+
+func (s *snapshot) enums(result *Snapshot) error {
+	rows, err := s.db.Query(fmt.Sprintf(QUERY_SNAPSHOT_ENUM, result.schema))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		object := snapshotObject{}
+		if err := rows.Scan(&name, &object.Name, &object.Definition); err != nil {
+			return err
+		}
+
+		result.enums[name] = &object
+	}
+
+	return rows.Err()
+}
+
+func (s *snapshot) functions(result *Snapshot) error {
+	rows, err := s.db.Query(fmt.Sprintf(QUERY_SNAPSHOT_FUNCTION, result.schema))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		object := snapshotObject{}
+		if err := rows.Scan(&name, &object.Name, &object.Arguments, &object.Definition); err != nil {
+			return err
+		}
+
+		result.functions[fmt.Sprintf("%s(%s)", name, object.Arguments)] = &object
+	}
+
+	return rows.Err()
+}
+
+func (s *snapshot) views(result *Snapshot) error {
+	rows, err := s.db.Query(fmt.Sprintf(QUERY_SNAPSHOT_VIEW, result.schema))
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var name, kind string
+		object := snapshotObject{}
+		if err := rows.Scan(&name, &object.Name, &kind, &object.Definition, &object.Columns); err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		if kind == "m" {
+			result.materializedViews[name] = &object
+
+			continue
+		}
+
+		result.views[name] = &object
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tool := NewCatalog(s.db)
+	for name, view := range result.materializedViews {
+		indexes, err := tool.objects(QUERY_CATALOG_INDEX, result.schema, name)
+		if err != nil {
+			return err
+		}
+
+		view.Indexes = make(map[string]*catalogObject, len(indexes))
+		for _, index := range indexes {
+			view.Indexes[index.Name] = index
+		}
+	}
+
+	return nil
+}
+
+func (s *snapshot) tables(result *Snapshot, excludes ...string) error {
+	rows, err := s.db.Query(fmt.Sprintf(QUERY_SNAPSHOT_TABLE, result.schema))
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		if slices.Contains(excludes, name) {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tool := NewCatalog(s.db)
+	for _, name := range names {
+		table, err := s.table(tool, result.schema, name)
+		if err != nil {
+			return err
+		}
+
+		result.tables[name] = table
+	}
+
+	return nil
+}
+
+func (s *snapshot) table(tool *catalog, schema string, name string) (*snapshotTable, error) {
+	qualified, err := tool.qualifiedName(schema, name)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := tool.columns(schema, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sequences, err := tool.sequences(schema, name)
+	if err != nil {
+		return nil, err
+	}
+
+	constraints, err := tool.objects(QUERY_CATALOG_CONSTRAINT, schema, name)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := tool.objects(QUERY_CATALOG_INDEX, schema, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ddl, err := tool.Generate(fmt.Sprintf("%s.%s", schema, name), true)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &snapshotTable{
+		Name:        qualified,
+		Ddl:         ddl,
+		Columns:     columns,
+		Sequences:   make(map[string]*catalogSequence, len(sequences)),
+		Constraints: make(map[string]*catalogObject, len(constraints)),
+		Indexes:     make(map[string]*catalogObject, len(indexes)),
+	}
+
+	for _, sequence := range sequences {
+		table.Sequences[sequence.Column] = sequence
+	}
+
+	for _, constraint := range constraints {
+		table.Constraints[constraint.Name] = constraint
+	}
+
+	for _, index := range indexes {
+		index.Definition = ddlReplacer.Replace(index.Definition)
+		table.Indexes[index.Name] = index
+	}
+
+	return table, nil
+}
+
+func (t *snapshotTable) column(name string) *catalogColumn {
+	for _, column := range t.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[T any](items map[string]T) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+func enumValues(values string) []string {
+	if values == "" {
+		return []string{}
+	}
+
+	return strings.Split(values, "#")
+}
+
+// viewOrder returns the views and materialized views of the snapshot, every one after the views it depends on.
+func (s *Snapshot) viewOrder() []Node {
+	graph := NewGraph()
+	for kind, views := range map[string]map[string]*snapshotObject{KIND_VIEW: s.views, KIND_MATERIALIZED_VIEW: s.materializedViews} {
+		for name := range views {
+			node := Node{Kind: kind, Name: name}
+			graph.AddNode(node)
+			for reference := range s.graph.edges[node] {
+				if s.view(reference) != nil {
+					graph.AddEdge(node, reference)
+				}
+			}
+		}
+	}
+
+	// views can't depend on each other in a cycle, Sort only fails on an inconsistent graph and returns what it sorted
+	order, _ := graph.Sort()
+
+	return order
+}
+
+func (s *Snapshot) view(node Node) *snapshotObject {
+	switch node.Kind {
+	case KIND_VIEW:
+		return s.views[node.Name]
+	case KIND_MATERIALIZED_VIEW:
+		return s.materializedViews[node.Name]
+	}
+
+	return nil
+}
@@ -3,9 +3,9 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"os"
 	"os/exec"
 	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/ad3n/kmt/v2/pkg/config"
@@ -21,6 +21,12 @@ var (
 		CREATE_INDEX, SECURE_CREATE_INDEX,
 		CREATE_UNIQUE_INDEX, SECURE_CREATE_UNIQUE_INDEX,
 	)
+
+	concurrentReplacer = strings.NewReplacer(
+		SECURE_CREATE_INDEX, CONCURRENT_CREATE_INDEX,
+		SECURE_CREATE_UNIQUE_INDEX, CONCURRENT_CREATE_UNIQUE_INDEX,
+		DROP_INDEX, CONCURRENT_DROP_INDEX,
+	)
 )
 
 type (
@@ -36,6 +42,8 @@ type (
 		Reference  *Migration
 		ForeignKey *Migration
 		Name       string
+
+		index *Migration
 	}
 )
 
@@ -70,7 +78,7 @@ func (t *Table) Detail(table string) (map[string]*Column, error) {
 	return result, nil
 }
 
-func (t *Table) Generate(name string, schemaOnly bool) *Ddl {
+func (t *Table) Generate(name string, schemaOnly bool) (*Ddl, error) {
 	options := []string{
 		"--no-comments",
 		"--no-publications",
@@ -83,11 +91,7 @@ func (t *Table) Generate(name string, schemaOnly bool) *Ddl {
 		"--no-privileges",
 		"--no-blobs",
 		"--clean",
-		"--username", t.config.User,
-		"--port", strconv.Itoa(t.config.Port),
-		"--host", t.config.Host,
 		"--table", name,
-		t.config.Name,
 	}
 
 	if schemaOnly {
@@ -96,9 +100,22 @@ func (t *Table) Generate(name string, schemaOnly bool) *Ddl {
 		options = append(options, "--inserts")
 	}
 
+	connection := *t.config
+	connection.Password = ""
+
+	dsn, err := connection.DSN()
+	if err != nil {
+		return nil, err
+	}
+
+	options = append(options, "--dbname", dsn)
+
 	cli := exec.Command(t.command, options...)
 
-	cli.Env = append(cli.Env, fmt.Sprintf("PGPASSWORD=%s", t.config.Password))
+	cli.Env = os.Environ()
+	if t.config.Password != "" {
+		cli.Env = append(cli.Env, fmt.Sprintf("PGPASSWORD=%s", t.config.Password))
+	}
 
 	var skip bool = false
 	var waitForSemicolon bool = false
@@ -117,7 +134,11 @@ func (t *Table) Generate(name string, schemaOnly bool) *Ddl {
 	var insertScript strings.Builder
 	var deleteScript strings.Builder
 
-	result, _ := cli.CombinedOutput()
+	result, err := cli.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("pg_dump of table %s failed, %w: %s", name, err, strings.TrimSpace(string(result)))
+	}
+
 	lines := strings.Split(string(result), "\n")
 	for n, line := range lines {
 		if t.skip(line) || skip {
@@ -228,6 +249,29 @@ func (t *Table) Generate(name string, schemaOnly bool) *Ddl {
 			UpScript:   upForeignScript.String(),
 			DownScript: downForeignScript.String(),
 		},
+	}, nil
+}
+
+// Indexes splits the indexes out of the table definition so they can be created separately.
+func (d *Ddl) Indexes() (*Migration, *Migration) {
+	if d.index == nil {
+		return d.Definition, &Migration{}
+	}
+
+	return &Migration{
+		UpScript:   strings.TrimSuffix(d.Definition.UpScript, d.index.UpScript),
+		DownScript: strings.TrimPrefix(d.Definition.DownScript, d.index.DownScript),
+	}, d.index
+}
+
+// Concurrently rewrites index statements to run concurrently in a migration without transaction.
+func (m *Migration) Concurrently() *Migration {
+	header := fmt.Sprintf("%s%s\n", config.HEADER_PREFIX, config.HEADER_NO_TRANSACTION)
+
+	return &Migration{
+		Name:       m.Name,
+		UpScript:   header + concurrentReplacer.Replace(m.UpScript),
+		DownScript: header + concurrentReplacer.Replace(m.DownScript),
 	}
 }
 
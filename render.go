@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ad3n/kmt/v2/pkg/command"
+	"github.com/ad3n/kmt/v2/pkg/config"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	OUTPUT_TABLE = "table"
+	OUTPUT_JSON  = "json"
+	OUTPUT_YAML  = "yaml"
+)
+
+func validateOutput(format string) error {
+	switch format {
+	case OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_YAML:
+		return nil
+	}
+
+	return fmt.Errorf("unknown output '%s', use table, json or yaml", format)
+}
+
+func render(format string, records any, renderTable func()) error {
+	switch format {
+	case OUTPUT_JSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "    ")
+
+		return encoder.Encode(records)
+	case OUTPUT_YAML:
+		content, err := yaml.Marshal(records)
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(content)
+
+		return err
+	}
+
+	renderTable()
+
+	return nil
+}
+
+func newTable() *table.Table {
+	t := table.New(os.Stdout)
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBrightBlack)
+	t.SetDividers(table.UnicodeRoundedDividers)
+
+	return t
+}
+
+func syncStatus(sync bool) string {
+	if sync {
+		return color.New(color.FgGreen).Sprint("v")
+	}
+
+	return color.New(color.FgRed, color.Bold).Sprint("x")
+}
+
+func attempts(history *config.History) string {
+	if history.Attempts <= 1 {
+		return strconv.Itoa(history.Attempts)
+	}
+
+	return fmt.Sprintf("%d (waited %s)", history.Attempts, history.RetryWait)
+}
+
+func versionTable(records []*command.VersionRecord) {
+	t := newTable()
+	t.AddHeaders("NO", "CONNECTION", "SCHEMA", "FILE", "VERSION", "SYNC", "DIFF")
+
+	for i, record := range records {
+		t.AddRow(
+			color.New(color.Bold).Sprint(i+1),
+			record.Connection,
+			record.Schema,
+			strconv.Itoa(int(record.File)),
+			strconv.Itoa(int(record.Database)),
+			syncStatus(record.Sync),
+			strconv.Itoa(record.Diff),
+		)
+	}
+
+	t.Render()
+}
+
+func compareTable(source string, compare string, records []*command.CompareRecord) {
+	t := newTable()
+	t.SetHeaders("NO", "SCHEMA", "FILE", strings.ToUpper(source), strings.ToUpper(compare), "SYNC", "DIFF")
+
+	for i, record := range records {
+		t.AddRow(
+			color.New(color.Bold).Sprint(i+1),
+			record.Schema,
+			strconv.Itoa(int(record.File)),
+			strconv.Itoa(int(record.SourceVersion)),
+			strconv.Itoa(int(record.CompareVersion)),
+			syncStatus(record.Sync),
+			strconv.Itoa(record.Diff),
+		)
+	}
+
+	t.Render()
+}
+
+func describeTable(records []*command.ColumnRecord) {
+	t := newTable()
+	t.AddHeaders("NO", "NAME", "DATA TYPE", "NULL?", "DEFAULT")
+
+	for i, record := range records {
+		t.AddRow(color.New(color.Bold).Sprint(i+1), color.New(color.Bold).Sprint(record.Name), record.DataType, syncStatus(record.Nullable), record.Default)
+	}
+
+	t.Render()
+}
+
+func inspectTable(dbs []string, records []*command.InspectRecord) {
+	t := newTable()
+
+	headers := []string{"NO", "NAME"}
+	subHeaders := []string{"NO", "NAME"}
+	colSpans := []int{1, 1}
+
+	for _, db := range dbs {
+		headers = append(headers, strings.ToUpper(db))
+		subHeaders = append(subHeaders, "DATA TYPE", "NULL?", "DEFAULT")
+		colSpans = append(colSpans, 3)
+	}
+
+	t.SetHeaders(headers...)
+	t.AddHeaders(subHeaders...)
+	t.SetHeaderColSpans(0, colSpans...)
+	t.SetAutoMergeHeaders(true)
+
+	missing := color.New(color.FgRed, color.Bold).Sprint("x")
+	for i, record := range records {
+		row := []string{color.New(color.Bold).Sprint(i + 1), record.Name}
+		if record.Different {
+			row = []string{color.New(color.FgRed, color.Bold).Sprint(i + 1), color.New(color.FgRed, color.Bold).Sprint(record.Name)}
+		}
+
+		for _, dbName := range dbs {
+			col := record.Columns[dbName]
+			if col == nil {
+				row = append(row, missing, missing, missing)
+
+				continue
+			}
+
+			row = append(row, col.DataType, syncStatus(col.Nullable), col.Default)
+		}
+
+		t.AddRow(row...)
+	}
+
+	t.Render()
+}